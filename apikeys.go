@@ -0,0 +1,357 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/youtube/v3"
+)
+
+// --- API Key Rotation ---
+
+// getEnvKeyList returns the comma-separated keys in listKey, falling back to
+// the single key in singleKey when the list is not set.
+func getEnvKeyList(listKey, singleKey string) []string {
+	var keys []string
+	for _, key := range strings.Split(os.Getenv(listKey), ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		if key := strings.TrimSpace(os.Getenv(singleKey)); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// isQuotaExceededError reports whether err says an API key's quota is used
+// up: a 403 or 429 with reason quotaExceeded or dailyLimitExceeded
+// (YouTube), or a 429 whose QuotaFailure detail names a per-day quota
+// (Gemini). Only these move to the next key; other 429s are short-term
+// rate limits, see isRateLimitError.
+func isQuotaExceededError(err error) bool {
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	for _, item := range apiErr.Errors {
+		if item.Reason == "quotaExceeded" || item.Reason == "dailyLimitExceeded" {
+			return true
+		}
+	}
+	for _, detail := range apiErr.Details {
+		fields, ok := detail.(map[string]interface{})
+		if !ok || !strings.HasSuffix(fmt.Sprint(fields["@type"]), "google.rpc.QuotaFailure") {
+			continue
+		}
+		violations, _ := fields["violations"].([]interface{})
+		for _, violation := range violations {
+			if v, ok := violation.(map[string]interface{}); ok && strings.Contains(fmt.Sprint(v["quotaId"]), "PerDay") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isRateLimitError reports whether err is an HTTP 429 that is not quota
+// exhaustion, such as a per-minute limit. It clears on its own, so the call
+// is retried on the same key after a pause instead of retiring the key.
+func isRateLimitError(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusTooManyRequests && !isQuotaExceededError(err)
+}
+
+const (
+	maxRateLimitRetries = 3               // Same-key retries of one call after isRateLimitError
+	rateLimitBackoff    = 2 * time.Second // First same-key wait, doubled on each retry
+)
+
+// maxRetryAfterWaits bounds how many times one call waits out a server's
+// retry delay before giving up.
+const maxRetryAfterWaits = 3
+
+// retryAfter returns the delay a Google API error asks callers to wait before
+// retrying: the Retry-After header (seconds or an HTTP date) or, failing
+// that, the retryDelay of a google.rpc.RetryInfo detail, which Gemini sends
+// with RESOURCE_EXHAUSTED.
+func retryAfter(err error) (time.Duration, bool) {
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) {
+		return 0, false
+	}
+	if value := apiErr.Header.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second, true
+		}
+		if at, err := http.ParseTime(value); err == nil {
+			return max(0, time.Until(at)), true
+		}
+	}
+	for _, detail := range apiErr.Details {
+		fields, ok := detail.(map[string]interface{})
+		if !ok || !strings.HasSuffix(fmt.Sprint(fields["@type"]), "google.rpc.RetryInfo") {
+			continue
+		}
+		if delay, ok := fields["retryDelay"].(string); ok {
+			if d, err := time.ParseDuration(delay); err == nil && d >= 0 {
+				return d, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// apiKeyRing tracks which of a service's configured API keys is in use. Keys
+// are only ever rotated forward, so each key is tried at most once per run.
+type apiKeyRing struct {
+	service       string
+	mu            sync.Mutex
+	keys          []string
+	active        int
+	maxRetryAfter time.Duration // Longest server-requested delay waited out; 0 never waits
+}
+
+func newAPIKeyRing(service string, keys []string) *apiKeyRing {
+	return &apiKeyRing{service: service, keys: keys}
+}
+
+// current returns the active key and its index.
+func (r *apiKeyRing) current() (string, int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.keys[r.active], r.active
+}
+
+// advance moves past the key at index from and returns the new active key. If
+// another caller already rotated away from that key, the active key is
+// returned unchanged. ok is false once every key has been used up.
+func (r *apiKeyRing) advance(from int) (key string, index int, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.active == from {
+		if r.active+1 >= len(r.keys) {
+			return "", r.active, false
+		}
+		r.active++
+		log.Printf("Rotated %s API key after quota exhaustion: now using key %d of %d.", r.service, r.active+1, len(r.keys))
+	}
+	return r.keys[r.active], r.active, true
+}
+
+// waitRetryAfter is called once every key is exhausted. If err says when to
+// retry, it sleeps that long, capped at maxRetryAfter, and reports whether
+// the call should be retried. waits counts the waits of one call and stops
+// it after maxRetryAfterWaits.
+func (r *apiKeyRing) waitRetryAfter(ctx context.Context, err error, waits *int) bool {
+	delay, ok := retryAfter(err)
+	if !ok || r.maxRetryAfter <= 0 || *waits >= maxRetryAfterWaits {
+		return false
+	}
+	*waits++
+	if delay > r.maxRetryAfter {
+		log.Printf("%s API asked to retry after %v; waiting the -max-retry-after cap of %v instead (wait %d/%d).", r.service, delay.Round(time.Second), r.maxRetryAfter, *waits, maxRetryAfterWaits)
+		delay = r.maxRetryAfter
+	} else {
+		log.Printf("%s API asked to retry after %v; waiting before retrying (wait %d/%d).", r.service, delay.Round(time.Millisecond), *waits, maxRetryAfterWaits)
+	}
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(delay):
+		return true
+	}
+}
+
+// backoff waits before a rate-limited call is retried on the same key: the
+// delay the server asked for, capped at maxRetryAfter when that is set, or
+// else rateLimitBackoff doubled on each retry. retries counts the retries of
+// one call; backoff reports false once maxRateLimitRetries is reached or ctx
+// ends. The rate limit is noted in the video's facts either way, for the
+// adaptive concurrency.
+func (r *apiKeyRing) backoff(ctx context.Context, err error, retries *int) bool {
+	videoFactsFrom(ctx).noteRateLimit()
+	if *retries >= maxRateLimitRetries {
+		return false
+	}
+	delay, ok := retryAfter(err)
+	if !ok {
+		delay = rateLimitBackoff << *retries
+	} else if r.maxRetryAfter > 0 && delay > r.maxRetryAfter {
+		delay = r.maxRetryAfter
+	}
+	*retries++
+	log.Printf("%s API rate limit hit; retrying on the same key in %v (retry %d/%d).", r.service, delay.Round(time.Millisecond), *retries, maxRateLimitRetries)
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(delay):
+		return true
+	}
+}
+
+// withRotation runs call until it succeeds or fails for a reason other than
+// its key, and returns how many times call ran along with its last error. A
+// rate-limited call is retried on the same key after backoff. A quota error
+// moves to the next key through rotate, and once every key is used up the
+// delay the server asked for is waited out. call returns the index of the
+// key it used, so that rotate only moves past that key.
+func (r *apiKeyRing) withRotation(ctx context.Context, call func() (int, error), rotate func(ctx context.Context, from int) error) (int, error) {
+	attempts := 0
+	retryWaits := 0
+	rateLimitRetries := 0
+	for {
+		attempts++
+		keyIndex, err := call()
+		if isRateLimitError(err) && r.backoff(ctx, err, &rateLimitRetries) {
+			continue
+		}
+		if err == nil || !isQuotaExceededError(err) {
+			return attempts, err
+		}
+		rotateErr := rotate(ctx, keyIndex)
+		if rotateErr == nil || r.waitRetryAfter(ctx, err, &retryWaits) {
+			continue
+		}
+		log.Printf("Warning: %s", runWarnings.add(warnAPIKey, "", "Could not rotate %s API key: %v", r.service, rotateErr))
+		return attempts, err
+	}
+}
+
+// rotatingYouTubeService holds the YouTube service for the active API key and
+// is shared by the playlist fetch and the workers. When a key runs out of
+// quota the first caller to notice recreates the service with the next key.
+type rotatingYouTubeService struct {
+	mu          sync.Mutex
+	keys        *apiKeyRing
+	httpTimeout time.Duration
+	service     *youtube.Service
+	keyIndex    int
+}
+
+func newRotatingYouTubeService(ctx context.Context, keys []string, httpTimeout, maxRetryAfter time.Duration) (*rotatingYouTubeService, error) {
+	ring := newAPIKeyRing("YouTube", keys)
+	ring.maxRetryAfter = maxRetryAfter
+	key, index := ring.current()
+	service, err := getYouTubeService(ctx, key, httpTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return &rotatingYouTubeService{keys: ring, httpTimeout: httpTimeout, service: service, keyIndex: index}, nil
+}
+
+// current returns the service for the active key along with the key's index,
+// which must be passed back to rotate if the call hits a quota error.
+func (s *rotatingYouTubeService) current() (*youtube.Service, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.service, s.keyIndex
+}
+
+// rotate switches to the next API key unless another caller already moved
+// away from the key at index from.
+func (s *rotatingYouTubeService) rotate(ctx context.Context, from int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key, index, ok := s.keys.advance(from)
+	if !ok {
+		return fmt.Errorf("all %d YouTube API keys have exceeded their quota", len(s.keys.keys))
+	}
+	if index == s.keyIndex {
+		return nil
+	}
+	service, err := getYouTubeService(ctx, key, s.httpTimeout)
+	if err != nil {
+		return err
+	}
+	s.service, s.keyIndex = service, index
+	return nil
+}
+
+// withKeyRotation runs call with the service for the active key, retrying it
+// on rate limits and quota errors (see apiKeyRing.withRotation), and returns
+// call's last error.
+func (s *rotatingYouTubeService) withKeyRotation(ctx context.Context, call func(service *youtube.Service) error) error {
+	_, err := s.keys.withRotation(ctx, func() (int, error) {
+		service, keyIndex := s.current()
+		return keyIndex, call(service)
+	}, s.rotate)
+	return err
+}
+
+// rotatingGeminiModel holds the Gemini model for the active API key and is
+// shared by all workers. When a key runs out of quota the first worker to
+// notice recreates the client with the next key.
+type rotatingGeminiModel struct {
+	mu          sync.Mutex
+	keys        *apiKeyRing
+	modelName   string
+	httpTimeout time.Duration
+	client      *genai.Client
+	model       *genai.GenerativeModel
+	keyIndex    int
+}
+
+func newGeminiModel(ctx context.Context, keys []string, modelName string, httpTimeout, maxRetryAfter time.Duration) (*rotatingGeminiModel, error) {
+	ring := newAPIKeyRing("Gemini", keys)
+	ring.maxRetryAfter = maxRetryAfter
+	key, index := ring.current()
+	client, err := genai.NewClient(ctx, apiClientOptions(key, httpTimeout)...)
+	if err != nil {
+		return nil, fmt.Errorf("genai.NewClient: %w", err)
+	}
+	return &rotatingGeminiModel{
+		keys:        ring,
+		modelName:   modelName,
+		httpTimeout: httpTimeout,
+		client:      client,
+		model:       client.GenerativeModel(modelName),
+		keyIndex:    index,
+	}, nil
+}
+
+// withKeyRotation runs call with the model and client for the active key,
+// retrying it on rate limits and quota errors (see apiKeyRing.withRotation),
+// and returns the number of requests made along with call's last error.
+func (g *rotatingGeminiModel) withKeyRotation(ctx context.Context, call func(model *genai.GenerativeModel, client *genai.Client) error) (int, error) {
+	return g.keys.withRotation(ctx, func() (int, error) {
+		g.mu.Lock()
+		model, client, keyIndex := g.model, g.client, g.keyIndex
+		g.mu.Unlock()
+		return keyIndex, call(model, client)
+	}, g.rotate)
+}
+
+// rotate switches to the next API key unless another worker already moved
+// away from the key at index from.
+func (g *rotatingGeminiModel) rotate(ctx context.Context, from int) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	key, index, ok := g.keys.advance(from)
+	if !ok {
+		return fmt.Errorf("all %d Gemini API keys have exceeded their quota", len(g.keys.keys))
+	}
+	if index == g.keyIndex {
+		return nil
+	}
+	// The previous client is left open since other workers may still be
+	// finishing calls on it.
+	client, err := genai.NewClient(ctx, apiClientOptions(key, g.httpTimeout)...)
+	if err != nil {
+		return fmt.Errorf("genai.NewClient: %w", err)
+	}
+	g.client, g.model, g.keyIndex = client, client.GenerativeModel(g.modelName), index
+	return nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"google.golang.org/api/googleapi"
+)
+
+func TestAPIKeyRingWithRotation(t *testing.T) {
+	quotaErr := &googleapi.Error{Code: http.StatusForbidden, Errors: []googleapi.ErrorItem{{Reason: "quotaExceeded"}}}
+	otherErr := errors.New("not found")
+	tests := []struct {
+		name         string
+		keys         []string
+		results      []error // What each call returns, in order
+		wantAttempts int
+		wantErr      error
+		wantKey      int // Active key afterwards
+	}{
+		{"success", []string{"a", "b"}, []error{nil}, 1, nil, 0},
+		{"other error", []string{"a", "b"}, []error{otherErr}, 1, otherErr, 0},
+		{"quota moves to the next key", []string{"a", "b"}, []error{quotaErr, nil}, 2, nil, 1},
+		{"every key exhausted", []string{"a", "b"}, []error{quotaErr, quotaErr}, 2, quotaErr, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ring := newAPIKeyRing("Test", tt.keys)
+			calls := 0
+			attempts, err := ring.withRotation(context.Background(), func() (int, error) {
+				_, keyIndex := ring.current()
+				calls++
+				return keyIndex, tt.results[calls-1]
+			}, func(ctx context.Context, from int) error {
+				if _, _, ok := ring.advance(from); !ok {
+					return errors.New("no keys left")
+				}
+				return nil
+			})
+			if attempts != tt.wantAttempts || !errors.Is(err, tt.wantErr) {
+				t.Errorf("withRotation() = %d, %v; want %d, %v", attempts, err, tt.wantAttempts, tt.wantErr)
+			}
+			if _, active := ring.current(); active != tt.wantKey {
+				t.Errorf("active key = %d, want %d", active, tt.wantKey)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import "fmt"
+
+// --- Audience Presets ---
+
+const (
+	audienceEngineers  = "engineers"
+	audienceExecutives = "executives"
+	audienceStudents   = "students"
+	audienceGeneral    = "general"
+)
+
+// audiencePrompts holds the instruction -audience appends to the summary
+// prompt for each preset.
+var audiencePrompts = map[string]string{
+	audienceEngineers:  "\n\nWrite for software engineers and other technical readers: keep precise technical terms, name the specific tools, techniques and trade-offs discussed, and include concrete details such as numbers, versions and limitations.",
+	audienceExecutives: "\n\nWrite for busy executives: lead with the main takeaway, focus on business impact, decisions, risks, costs and timelines, avoid technical jargon, and leave out implementation details.",
+	audienceStudents:   "\n\nWrite for students learning the subject: explain the key concepts in clear language, briefly define technical terms when they first appear, and present the ideas in the order that makes them easiest to follow.",
+	audienceGeneral:    "\n\nWrite for a general audience with no background in the subject: use plain everyday language, avoid jargon, and focus on why the content matters.",
+}
+
+func validateAudience(audience string) error {
+	if _, ok := audiencePrompts[audience]; audience != "" && !ok {
+		return fmt.Errorf("invalid -audience %q: must be %s, %s, %s or %s", audience, audienceEngineers, audienceExecutives, audienceStudents, audienceGeneral)
+	}
+	return nil
+}
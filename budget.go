@@ -0,0 +1,93 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// --- Gemini Usage ---
+
+// usageTracker totals what the run sends to and gets back from Gemini: the
+// calls and token counts each response reports, and the transcript
+// characters sent. The same totals drive the -max-cost and -max-total-chars
+// budgets and the -stats-only report. A nil tracker records nothing and
+// never runs out.
+type usageTracker struct {
+	maxCost          float64 // Dollars; 0 when -max-cost is not set
+	inputPricePer1K  float64
+	outputPricePer1K float64
+	maxChars         int64 // 0 when -max-total-chars is not set
+
+	mu           sync.Mutex
+	calls        int
+	inputTokens  int64
+	outputTokens int64
+	chars        int64
+	charsSkipped int // Videos refused by reserve
+}
+
+// record adds the token usage of one response.
+func (u *usageTracker) record(usage *genai.UsageMetadata) {
+	if u == nil || usage == nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.calls++
+	u.inputTokens += int64(usage.PromptTokenCount)
+	u.outputTokens += int64(usage.CandidatesTokenCount)
+}
+
+// spent returns the estimated cost so far in dollars.
+func (u *usageTracker) spent() float64 {
+	if u == nil {
+		return 0
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return float64(u.inputTokens)/1000*u.inputPricePer1K + float64(u.outputTokens)/1000*u.outputPricePer1K
+}
+
+// costExhausted reports whether the estimated spend has reached -max-cost.
+func (u *usageTracker) costExhausted() bool {
+	return u != nil && u.maxCost > 0 && u.spent() >= u.maxCost
+}
+
+// reserve counts chars against -max-total-chars and reports whether they may
+// be sent. Once the total has reached the budget nothing more is allowed; the
+// transcript that crosses it is still sent, so usage can overshoot by one.
+func (u *usageTracker) reserve(chars int64) bool {
+	if u == nil {
+		return true
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.maxChars > 0 && u.chars >= u.maxChars {
+		u.charsSkipped++
+		return false
+	}
+	u.chars += chars
+	return true
+}
+
+// charsSent returns the transcript characters reserved so far and the number
+// of videos refused because the budget was reached.
+func (u *usageTracker) charsSent() (sent int64, skipped int) {
+	if u == nil {
+		return 0, 0
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.chars, u.charsSkipped
+}
+
+// tokens returns the Gemini calls and token totals recorded so far.
+func (u *usageTracker) tokens() (calls int, input, output int64) {
+	if u == nil {
+		return 0, 0, 0
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.calls, u.inputTokens, u.outputTokens
+}
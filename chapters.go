@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- Description Chapters ---
+
+// minChapterCount is the fewest timestamped lines treated as a chapter list,
+// so a description that merely mentions one timestamp is ignored.
+const minChapterCount = 2
+
+// chapterLinePattern matches description lines such as "00:00 Intro",
+// "1:02:15 - Q&A" or "(12:30) Wrap up".
+var chapterLinePattern = regexp.MustCompile(`^\s*[-*•]?\s*\(?((?:\d{1,2}:)?\d{1,2}:\d{2})\)?\s*[-–—:|]?\s*(.+?)\s*$`)
+
+// Chapter is a timestamped section listed in a video's description.
+type Chapter struct {
+	Start time.Duration
+	Title string
+}
+
+// parseChapters extracts chapters from a video description. It returns nil
+// when the description does not contain a chapter list.
+func parseChapters(description string) []Chapter {
+	var chapters []Chapter
+	for _, line := range strings.Split(description, "\n") {
+		match := chapterLinePattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		start, err := parseChapterTimestamp(match[1])
+		if err != nil {
+			continue
+		}
+		chapters = append(chapters, Chapter{Start: start, Title: match[2]})
+	}
+	if len(chapters) < minChapterCount {
+		return nil
+	}
+	return chapters
+}
+
+// parseChapterTimestamp converts "MM:SS" or "H:MM:SS" into a duration.
+func parseChapterTimestamp(timestamp string) (time.Duration, error) {
+	var total time.Duration
+	for _, part := range strings.Split(timestamp, ":") {
+		value, err := strconv.Atoi(part)
+		if err != nil {
+			return 0, fmt.Errorf("invalid chapter timestamp %q: %w", timestamp, err)
+		}
+		total = total*60 + time.Duration(value)
+	}
+	return total * time.Second, nil
+}
+
+// formatChapterTimestamp renders d as "MM:SS", or "H:MM:SS" past an hour.
+func formatChapterTimestamp(d time.Duration) string {
+	seconds := int(d / time.Second)
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds%3600/60, seconds%60)
+	}
+	return fmt.Sprintf("%02d:%02d", seconds/60, seconds%60)
+}
+
+// formatChapters lists chapters one per line for inclusion in a prompt.
+func formatChapters(chapters []Chapter) string {
+	var builder strings.Builder
+	for _, chapter := range chapters {
+		fmt.Fprintf(&builder, "%s %s\n", formatChapterTimestamp(chapter.Start), chapter.Title)
+	}
+	return strings.TrimRight(builder.String(), "\n")
+}
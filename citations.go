@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/asticode/go-astisub"
+)
+
+// --- Timestamp Citations ---
+
+const citePrompt = "\n\nEach transcript line starts with its [timestamp]. After each key point, cite where the video says it as (MM:SS), or (H:MM:SS) past an hour, copying a timestamp from the transcript."
+
+var (
+	transcriptTimestampPattern = regexp.MustCompile(`(?m)^\[((?:\d+:)?\d{1,2}:\d{2})\] `)
+	citationPattern            = regexp.MustCompile(`\s*\(((?:\d+:)?\d{1,2}:\d{2})\)`)
+)
+
+// buildTimestampedTranscript puts each cue on its own line prefixed with its
+// start time, e.g. "[02:15] text", so the model can cite where points are
+// made under -cite.
+func buildTimestampedTranscript(subs *astisub.Subtitles) string {
+	var lines []string
+	for _, item := range subs.Items {
+		var words []string
+		for _, line := range item.Lines {
+			for _, lineItem := range line.Items {
+				words = append(words, strings.Fields(lineItem.Text)...)
+			}
+		}
+		if len(words) > 0 {
+			lines = append(lines, fmt.Sprintf("[%s] %s", formatChapterTimestamp(item.StartAt), strings.Join(words, " ")))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// lastTranscriptTimestamp returns the start of the last line of a
+// timestamped transcript. ok is false when the transcript has no timestamps,
+// e.g. a stored transcript saved without -cite.
+func lastTranscriptTimestamp(transcript string) (last time.Duration, ok bool) {
+	for _, match := range transcriptTimestampPattern.FindAllStringSubmatch(transcript, -1) {
+		if start, err := parseChapterTimestamp(match[1]); err == nil && start >= last {
+			last, ok = start, true
+		}
+	}
+	return last, ok
+}
+
+// validateCitations drops the (MM:SS) citations in summary that point past
+// the end of the transcript and so cannot be real, returning the cleaned
+// summary with the number of citations kept and dropped.
+func validateCitations(summary, transcript string) (cleaned string, kept, dropped int) {
+	last, ok := lastTranscriptTimestamp(transcript)
+	cleaned = citationPattern.ReplaceAllStringFunc(summary, func(citation string) string {
+		at, err := parseChapterTimestamp(citationPattern.FindStringSubmatch(citation)[1])
+		if err != nil || !ok || at > last {
+			dropped++
+			return ""
+		}
+		kept++
+		return citation
+	})
+	return cleaned, kept, dropped
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// --- Temp Directory Cleanup ---
+
+const (
+	cleanupAttempts   = 3
+	cleanupRetryDelay = 500 * time.Millisecond
+	// staleTempFileAge is how long a temp file must have been untouched
+	// before -cleanup-on-start removes it. Files a concurrent run is still
+	// downloading or parsing are much younger than this.
+	staleTempFileAge = time.Hour
+)
+
+// tempFileSuffixes are the files Summify and yt-dlp leave in the temp dir:
+// subtitles, SponsorBlock segments, and yt-dlp's partial downloads.
+var tempFileSuffixes = []string{".vtt", ".srt", sponsorBlockFileSuffix, ".part", ".ytdl"}
+
+// removeTempDir removes dir, retrying with a growing delay since files that
+// are still held open (e.g. by antivirus scanners on Windows) often become
+// removable moments later.
+func removeTempDir(dir string) error {
+	var err error
+	for attempt := 1; attempt <= cleanupAttempts; attempt++ {
+		if err = os.RemoveAll(dir); err == nil {
+			return nil
+		}
+		if attempt < cleanupAttempts {
+			delay := time.Duration(attempt) * cleanupRetryDelay
+			log.Printf("Cleanup attempt %d/%d for %s failed: %v; retrying in %v.", attempt, cleanupAttempts, dir, err, delay)
+			time.Sleep(delay)
+		}
+	}
+	return err
+}
+
+// purgeStaleTempFiles removes subtitle and partial-download files in dir
+// left by earlier runs that crashed or failed to clean up. Only files older
+// than staleTempFileAge are removed, so a run working in the same directory
+// at the same time keeps its files. It returns how many files were removed.
+func purgeStaleTempFiles(dir string, now time.Time) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for _, entry := range entries {
+		if entry.IsDir() || !hasTempFileSuffix(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || now.Sub(info.ModTime()) < staleTempFileAge {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		if err := os.Remove(path); err != nil {
+			log.Printf("Warning: %s", runWarnings.add(warnCleanup, "", "Could not remove stale temp file %s: %v", path, err))
+			continue
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+func hasTempFileSuffix(name string) bool {
+	for _, suffix := range tempFileSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"io"
+	"os"
+)
+
+// --- Colored Report Output ---
+
+const (
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+	ansiReset  = "\x1b[0m"
+)
+
+// reportColors wraps report status text in ANSI colors when enabled, and
+// returns it unchanged otherwise.
+type reportColors struct {
+	enabled bool
+}
+
+// newReportColors decides whether the report written to w is colored:
+// always with -color, never with -no-color, and otherwise only when w is a
+// terminal and the NO_COLOR environment variable is unset.
+func newReportColors(w io.Writer, cfg *AppConfig) reportColors {
+	switch {
+	case cfg.Color:
+		return reportColors{enabled: true}
+	case cfg.NoColor || os.Getenv("NO_COLOR") != "":
+		return reportColors{}
+	}
+	file, ok := w.(*os.File)
+	return reportColors{enabled: ok && fileIsTerminal(file)}
+}
+
+func (c reportColors) wrap(color, text string) string {
+	if !c.enabled {
+		return text
+	}
+	return color + text + ansiReset
+}
+
+func (c reportColors) success(text string) string { return c.wrap(ansiGreen, text) }
+func (c reportColors) failure(text string) string { return c.wrap(ansiRed, text) }
+func (c reportColors) warning(text string) string { return c.wrap(ansiYellow, text) }
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/youtube/v3"
+)
+
+// --- Video Comments ---
+
+const (
+	maxCommentsPerPage          = 100
+	commentsSummaryPromptFormat = "These are the top viewer comments on the YouTube video %q. In 2-3 sentences, summarize the audience's overall sentiment and the main points viewers raise:\n\n%s"
+)
+
+// isCommentsDisabledError reports whether err is the YouTube API's response
+// for a video whose comments have been turned off.
+func isCommentsDisabledError(err error) bool {
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	for _, item := range apiErr.Errors {
+		if item.Reason == "commentsDisabled" {
+			return true
+		}
+	}
+	return false
+}
+
+// getTopComments returns the text of up to maxComments top-level comments on
+// a video, ordered by relevance. Videos with comments disabled return no
+// comments and no error.
+func getTopComments(ctx context.Context, yt *rotatingYouTubeService, videoID string, maxComments int) ([]string, error) {
+	var comments []string
+	nextPageToken := ""
+	for len(comments) < maxComments {
+		var response *youtube.CommentThreadListResponse
+		err := yt.withKeyRotation(ctx, func(service *youtube.Service) error {
+			call := service.CommentThreads.List([]string{"snippet"})
+			call = call.VideoId(videoID)
+			call = call.Order("relevance")
+			call = call.TextFormat("plainText")
+			call = call.MaxResults(int64(min(maxComments-len(comments), maxCommentsPerPage)))
+			if nextPageToken != "" {
+				call = call.PageToken(nextPageToken)
+			}
+			var err error
+			response, err = call.Context(ctx).Do()
+			return err
+		})
+		if err != nil {
+			if isCommentsDisabledError(err) {
+				log.Printf("Video %s: Comments are disabled.", videoID)
+				return nil, nil
+			}
+			return nil, fmt.Errorf("CommentThreads.List call failed for video %s: %w", videoID, err)
+		}
+		for _, thread := range response.Items {
+			if thread.Snippet == nil || thread.Snippet.TopLevelComment == nil || thread.Snippet.TopLevelComment.Snippet == nil {
+				continue
+			}
+			if text := strings.TrimSpace(thread.Snippet.TopLevelComment.Snippet.TextDisplay); text != "" {
+				comments = append(comments, text)
+			}
+		}
+		nextPageToken = response.NextPageToken
+		if nextPageToken == "" {
+			break
+		}
+	}
+	return comments, nil
+}
+
+// summarizeVideoComments fetches the video's top comments and asks Gemini for
+// a short audience-sentiment summary. It returns "" if there are no comments.
+func summarizeVideoComments(ctx context.Context, yt *rotatingYouTubeService, gemini *rotatingGeminiModel, video VideoDetails, cfg *AppConfig) (string, error) {
+	comments, err := getTopComments(ctx, yt, video.ID, cfg.IncludeComments)
+	if err != nil {
+		return "", err
+	}
+	if len(comments) == 0 {
+		return "", nil
+	}
+	log.Printf("Video %s: Fetched %d comments for sentiment summary.", video.ID, len(comments))
+
+	var commentList strings.Builder
+	for _, comment := range comments {
+		commentList.WriteString("- ")
+		commentList.WriteString(strings.ReplaceAll(comment, "\n", " "))
+		commentList.WriteString("\n")
+	}
+	summary, _, err := generateWithGemini(ctx, gemini, fmt.Sprintf(commentsSummaryPromptFormat, video.Title, commentList.String()), cfg)
+	return summary, err
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+)
+
+// --- Model Comparison ---
+
+// ModelSummary is one model's summary of a video under -compare-models.
+type ModelSummary struct {
+	Model     string
+	Summary   string
+	Latency   time.Duration
+	Attempts  int  // Summary requests made, including retries after key rotation
+	Truncated bool // The model hit its output token limit
+	Err       error
+}
+
+// comparedModel is a Gemini client for one of the -compare-models models.
+type comparedModel struct {
+	name   string
+	gemini *rotatingGeminiModel
+}
+
+// newComparedModels creates and validates a client for each -compare-models
+// model, sharing the configured Gemini API keys.
+func newComparedModels(ctx context.Context, cfg *AppConfig) ([]comparedModel, error) {
+	var models []comparedModel
+	for _, name := range cfg.CompareModels {
+		gemini, err := newGeminiModel(ctx, cfg.GeminiAPIKeys, name, cfg.HTTPTimeout, cfg.MaxRetryAfter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Gemini client for %s: %w", name, err)
+		}
+		if err := validateGeminiModel(ctx, gemini.client, name); err != nil {
+			return nil, err
+		}
+		models = append(models, comparedModel{name: name, gemini: gemini})
+	}
+	return models, nil
+}
+
+// summarizeWithEachModel summarizes the transcript once per model, one after
+// the other so the latencies are comparable.
+func summarizeWithEachModel(ctx context.Context, models []comparedModel, video VideoDetails, transcript string, chapters []Chapter, template string, cfg *AppConfig) []ModelSummary {
+	summaries := make([]ModelSummary, 0, len(models))
+	for _, model := range models {
+		start := time.Now()
+		summary, attempts, err := summarizeTranscriptWithGemini(ctx, model.gemini, transcript, chapters, template, cfg)
+		result := ModelSummary{Model: model.name, Summary: summary, Latency: time.Since(start), Attempts: attempts, Err: err}
+		if isTruncatedResponse(err) {
+			result.Truncated = true
+			log.Printf("  Video %s (%s): Warning: %s", video.ID, video.Title, runWarnings.add(warnSummary, video.ID, "%s: %v", model.name, err))
+		} else if err != nil {
+			log.Printf("  Video %s (%s): Error summarizing with %s: %v", video.ID, video.Title, model.name, err)
+			result.Summary = ""
+		} else {
+			log.Printf("  Video %s (%s): Summarized with %s in %v.", video.ID, video.Title, model.name, result.Latency.Round(time.Millisecond))
+		}
+		summaries = append(summaries, result)
+	}
+	return summaries
+}
+
+// firstModelSummary returns the summary of the first model that produced
+// one, which stands in as the video's Summary for the index and exports. If
+// every model failed, the first error is returned.
+func firstModelSummary(summaries []ModelSummary) (string, error) {
+	var firstErr error
+	for _, s := range summaries {
+		if s.Summary != "" {
+			return strings.TrimSpace(s.Summary), nil
+		}
+		if firstErr == nil {
+			firstErr = s.Err
+		}
+	}
+	return "", firstErr
+}
+
+// modelSummaryStats returns the most summary requests any one model needed,
+// so that a comparison without retries counts as a single attempt, and
+// reports whether any model's summary was cut off at the token limit. Each
+// model's own count stays in its ModelSummary.
+func modelSummaryStats(summaries []ModelSummary) (attempts int, truncated bool) {
+	for _, s := range summaries {
+		attempts = max(attempts, s.Attempts)
+		truncated = truncated || s.Truncated
+	}
+	return attempts, truncated
+}
+
+// printModelSummaries prints each model's summary, labelled with the model
+// and how long it took, for the end-of-run report.
+func printModelSummaries(w io.Writer, colors reportColors, summaries []ModelSummary, previewWords int) {
+	for _, s := range summaries {
+		switch {
+		case s.Summary != "":
+			notes := ""
+			if s.Truncated {
+				notes = ", truncated"
+			}
+			fmt.Fprintf(w, "%s %s\n", colors.success(fmt.Sprintf("Summary [%s, %v%s]:", s.Model, s.Latency.Round(time.Millisecond), notes)), previewText(strings.TrimSpace(s.Summary), previewWords))
+		case s.Err != nil:
+			fmt.Fprintln(w, colors.failure(fmt.Sprintf("Summary [%s]: Error: %v", s.Model, s.Err)))
+		}
+	}
+}
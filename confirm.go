@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// --- Large Run Confirmation ---
+
+// estimateLLMCalls returns the number of Gemini requests a run over
+// videoCount videos is expected to make when nothing fails.
+func estimateLLMCalls(videoCount int, cfg *AppConfig) int {
+	if cfg.TranscriptsOnly {
+		return 0
+	}
+	perVideo := 1
+	if len(cfg.CompareModels) > 0 {
+		perVideo = len(cfg.CompareModels)
+	}
+	if cfg.IncludeComments > 0 {
+		perVideo++
+	}
+	return videoCount * perVideo
+}
+
+// stdinIsTerminal reports whether stdin is an interactive terminal rather
+// than a pipe, file, or /dev/null.
+func stdinIsTerminal() bool {
+	return fileIsTerminal(os.Stdin)
+}
+
+// fileIsTerminal reports whether file is a character device such as a
+// terminal.
+func fileIsTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// confirmLargeRun asks on in whether to continue with videoCount videos and
+// reports the answer. Anything other than "y" or "yes" declines.
+func confirmLargeRun(in io.Reader, out io.Writer, videoCount int, cfg *AppConfig) bool {
+	fmt.Fprintf(out, "About to process %d videos (about %d Gemini calls). Continue? [y/N]: ", videoCount, estimateLLMCalls(videoCount, cfg))
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- CSV Export ---
+
+const defaultCSVColumns = "video_id,title,summary,status"
+
+// csvColumn is one column -csv-columns can select. value is given the
+// video, its result, and whether a result exists.
+type csvColumn struct {
+	name  string
+	value func(video VideoDetails, result ProcessingResult, ok bool) string
+}
+
+// csvColumns lists the known columns in the order shown in error messages.
+var csvColumns = []csvColumn{
+	{"video_id", func(v VideoDetails, _ ProcessingResult, _ bool) string { return v.ID }},
+	{"title", func(v VideoDetails, _ ProcessingResult, _ bool) string { return v.Title }},
+	{"generated_title", func(_ VideoDetails, r ProcessingResult, _ bool) string { return r.GeneratedTitle }},
+	{"url", func(v VideoDetails, _ ProcessingResult, _ bool) string {
+		return "https://www.youtube.com/watch?v=" + v.ID
+	}},
+	{"source", func(v VideoDetails, _ ProcessingResult, _ bool) string { return v.SourceLabel }},
+	{"published_at", func(v VideoDetails, _ ProcessingResult, _ bool) string { return formatCSVTime(v.PublishedAt) }},
+	{"added_at", func(v VideoDetails, _ ProcessingResult, _ bool) string { return formatCSVTime(v.AddedAt) }},
+	{"summary", func(_ VideoDetails, r ProcessingResult, _ bool) string { return r.Summary }},
+	{"status", func(_ VideoDetails, r ProcessingResult, ok bool) string { return resultStatus(r, ok) }},
+	{"error", func(_ VideoDetails, r ProcessingResult, _ bool) string {
+		if r.Err == nil {
+			return ""
+		}
+		return r.Err.Error()
+	}},
+	{"language", func(_ VideoDetails, r ProcessingResult, _ bool) string { return r.Language }},
+	{"transcript_chars", func(_ VideoDetails, r ProcessingResult, _ bool) string { return strconv.Itoa(r.TranscriptChars) }},
+	{"transcript_path", func(_ VideoDetails, r ProcessingResult, _ bool) string { return r.TranscriptPath }},
+	{"notes_path", func(_ VideoDetails, r ProcessingResult, _ bool) string { return r.NotesPath }},
+	{"citations", func(_ VideoDetails, r ProcessingResult, _ bool) string { return strconv.Itoa(r.Citations) }},
+	{"truncated", func(_ VideoDetails, r ProcessingResult, _ bool) string { return strconv.FormatBool(r.Truncated) }},
+	{"elapsed_seconds", func(_ VideoDetails, r ProcessingResult, _ bool) string {
+		return strconv.FormatFloat(r.Elapsed.Seconds(), 'f', 1, 64)
+	}},
+}
+
+func formatCSVTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// parseCSVColumns resolves a comma-separated -csv-columns list, rejecting
+// unknown and repeated names.
+func parseCSVColumns(list string) ([]csvColumn, error) {
+	known := make(map[string]csvColumn, len(csvColumns))
+	var names []string
+	for _, column := range csvColumns {
+		known[column.name] = column
+		names = append(names, column.name)
+	}
+	var columns []csvColumn
+	seen := make(map[string]bool)
+	for _, name := range splitCommaList(list) {
+		column, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown -csv-columns column %q: must be one of %s", name, strings.Join(names, ", "))
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("-csv-columns lists %q more than once", name)
+		}
+		seen[name] = true
+		columns = append(columns, column)
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("-csv-columns needs at least one column")
+	}
+	return columns, nil
+}
+
+// writeResultsCSV writes a header row and one row per video, in playlist
+// order, and returns the number of rows written. An empty summary cell gets
+// placeholder instead (-empty-placeholder).
+func writeResultsCSV(path string, columns []csvColumn, videos []VideoDetails, allResults map[string]ProcessingResult, placeholder string, perm os.FileMode) (int, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, filePerm(perm))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create CSV file %s: %w", path, err)
+	}
+
+	writer := csv.NewWriter(file)
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = column.name
+	}
+	writer.Write(header)
+	for _, video := range videos {
+		result, ok := allResults[video.ID]
+		row := make([]string, len(columns))
+		for i, column := range columns {
+			row[i] = column.value(video, result, ok)
+			if column.name == "summary" && row[i] == "" {
+				row[i] = placeholder
+			}
+		}
+		writer.Write(row)
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		file.Close()
+		return 0, fmt.Errorf("failed to write CSV file %s: %w", path, err)
+	}
+	if err := file.Close(); err != nil {
+		return 0, fmt.Errorf("failed to write CSV file %s: %w", path, err)
+	}
+	return len(videos), nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// --- Run Warnings ---
+
+// Warning categories, for grouping related problems in the report.
+const (
+	warnPlaylist   = "playlist"
+	warnAPIKey     = "api-key"
+	warnConfig     = "config"
+	warnTranscript = "transcript"
+	warnSummary    = "summary"
+	warnOutput     = "output"
+	warnCleanup    = "cleanup"
+)
+
+// runWarning is one problem worth reviewing after the run. VideoID is empty
+// for problems that do not concern a single video.
+type runWarning struct {
+	Category string `json:"category"`
+	VideoID  string `json:"video_id,omitempty"`
+	Message  string `json:"message"`
+}
+
+// warningLog collects the run's warnings from every worker so they can be
+// listed at the end of the report and written with -warnings-json.
+type warningLog struct {
+	mu       sync.Mutex
+	warnings []runWarning
+}
+
+// runWarnings collects the warnings of the current run.
+var runWarnings = &warningLog{}
+
+// add records a warning and returns its message, so the caller can log it
+// in its usual format.
+func (l *warningLog) add(category, videoID, format string, args ...any) string {
+	message := fmt.Sprintf(format, args...)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.warnings = append(l.warnings, runWarning{Category: category, VideoID: videoID, Message: message})
+	return message
+}
+
+func (l *warningLog) list() []runWarning {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]runWarning(nil), l.warnings...)
+}
+
+// printWarnings writes the "Warnings (N)" section of the report; nothing is
+// written when there were none.
+func printWarnings(w io.Writer, colors reportColors, warnings []runWarning) {
+	if len(warnings) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n%s\n", colors.warning(fmt.Sprintf("--- Warnings (%d) ---", len(warnings))))
+	for _, warning := range warnings {
+		if warning.VideoID != "" {
+			fmt.Fprintf(w, "[%s] Video %s: %s\n", warning.Category, warning.VideoID, warning.Message)
+		} else {
+			fmt.Fprintf(w, "[%s] %s\n", warning.Category, warning.Message)
+		}
+	}
+}
+
+// writeWarningsJSON writes the warnings to path as a JSON array.
+func writeWarningsJSON(path string, warnings []runWarning, perm os.FileMode) error {
+	if warnings == nil {
+		warnings = []runWarning{}
+	}
+	data, err := json.MarshalIndent(warnings, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), filePerm(perm)); err != nil {
+		return fmt.Errorf("failed to write warnings file %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/googleapi"
+)
+
+// --- Doctor ---
+
+// doctorCheck is one line of the -doctor report.
+type doctorCheck struct {
+	name string
+	run  func(ctx context.Context) (detail string, err error)
+}
+
+// runDoctor runs each setup check, prints a pass/fail report and reports
+// whether everything passed. No videos are processed.
+func runDoctor(ctx context.Context, cfg *AppConfig) bool {
+	checks := []doctorCheck{
+		{"yt-dlp", checkYtDlp},
+		{"Temp directory", func(ctx context.Context) (string, error) {
+			return checkWritableDir(cfg.TempTranscriptDir, cfg.TempPerms)
+		}},
+		{"Network", func(ctx context.Context) (string, error) { return checkNetwork(ctx, cfg) }},
+		{"YouTube API key and playlist", func(ctx context.Context) (string, error) { return checkYouTubeAccess(ctx, cfg) }},
+		{"Gemini API key and model", func(ctx context.Context) (string, error) { return checkGeminiAccess(ctx, cfg) }},
+	}
+
+	fmt.Println("--- Summify Doctor ---")
+	passed := true
+	for _, check := range checks {
+		checkCtx, cancel := context.WithTimeout(ctx, defaultModelInfoTimeout)
+		detail, err := check.run(checkCtx)
+		cancel()
+		if err != nil {
+			passed = false
+			fmt.Printf("[FAIL] %s: %v\n", check.name, err)
+		} else {
+			fmt.Printf("[PASS] %s: %s\n", check.name, detail)
+		}
+	}
+	if passed {
+		fmt.Println("All checks passed.")
+	} else {
+		fmt.Println("Some checks failed; fix them before running Summify.")
+	}
+	return passed
+}
+
+func checkYtDlp(ctx context.Context) (string, error) {
+	output, err := exec.CommandContext(ctx, ytDlpCommand, "--version").Output()
+	if err != nil {
+		return "", fmt.Errorf("could not run %s (is it installed and on PATH?): %w", ytDlpCommand, err)
+	}
+	return "version " + strings.TrimSpace(string(output)), nil
+}
+
+func checkWritableDir(dir string, perm os.FileMode) (string, error) {
+	if err := makeDir(dir, perm); err != nil {
+		return "", fmt.Errorf("cannot create %s: %w", dir, err)
+	}
+	file, err := os.CreateTemp(dir, "doctor-*")
+	if err != nil {
+		return "", fmt.Errorf("cannot write to %s: %w", dir, err)
+	}
+	file.Close()
+	os.Remove(file.Name())
+	return dir + " is writable", nil
+}
+
+func checkNetwork(ctx context.Context, cfg *AppConfig) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, "https://www.youtube.com/", nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := (&http.Client{Timeout: cfg.HTTPTimeout}).Do(req)
+	if err != nil {
+		return "", fmt.Errorf("cannot reach www.youtube.com: %w", err)
+	}
+	resp.Body.Close()
+	return "www.youtube.com reachable", nil
+}
+
+// checkYouTubeAccess lists a single item of the configured playlist, which
+// validates the key and the playlist ID for one quota unit.
+func checkYouTubeAccess(ctx context.Context, cfg *AppConfig) (string, error) {
+	if len(cfg.YoutubeAPIKeys) == 0 {
+		return "", fmt.Errorf("%s or %s is not set", envYoutubeAPIKey, envYoutubeAPIKeys)
+	}
+	service, err := getYouTubeService(ctx, cfg.YoutubeAPIKeys[0], cfg.HTTPTimeout)
+	if err != nil {
+		return "", err
+	}
+	response, err := service.PlaylistItems.List([]string{"id"}).PlaylistId(cfg.PlaylistID).MaxResults(1).Context(ctx).Do()
+	var apiErr *googleapi.Error
+	if err != nil && errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+		return "", fmt.Errorf("key works but playlist %s was not found (is it private?)", cfg.PlaylistID)
+	}
+	if err != nil {
+		return "", fmt.Errorf("YouTube API call failed: %w", err)
+	}
+	if response.PageInfo == nil {
+		return fmt.Sprintf("key accepted, playlist %s found", cfg.PlaylistID), nil
+	}
+	return fmt.Sprintf("key accepted, playlist %s has %d videos", cfg.PlaylistID, response.PageInfo.TotalResults), nil
+}
+
+// checkGeminiAccess makes a tiny generate call with the configured model.
+func checkGeminiAccess(ctx context.Context, cfg *AppConfig) (string, error) {
+	if len(cfg.GeminiAPIKeys) == 0 {
+		return "", fmt.Errorf("%s or %s is not set", envGeminiAPIKey, envGeminiAPIKeys)
+	}
+	client, err := genai.NewClient(ctx, apiClientOptions(cfg.GeminiAPIKeys[0], cfg.HTTPTimeout)...)
+	if err != nil {
+		return "", fmt.Errorf("genai.NewClient: %w", err)
+	}
+	defer client.Close()
+	if _, err := client.GenerativeModel(cfg.GeminiModel).GenerateContent(ctx, genai.Text("Reply with OK.")); err != nil {
+		return "", fmt.Errorf("test request to %s failed: %w", cfg.GeminiModel, err)
+	}
+	return "model " + cfg.GeminiModel + " answered", nil
+}
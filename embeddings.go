@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// --- Summary Embeddings ---
+
+const defaultEmbeddingModel = "text-embedding-004"
+
+// summaryEmbedding is one line of the -embeddings NDJSON file.
+type summaryEmbedding struct {
+	VideoID   string    `json:"video_id"`
+	Title     string    `json:"title"`
+	Model     string    `json:"model"`
+	Embedding []float32 `json:"embedding"`
+}
+
+// embedText returns the embedding vector for text, rotating the Gemini API key
+// on quota errors the same way generateWithGemini does.
+func embedText(ctx context.Context, gemini *rotatingGeminiModel, modelName, text string, cfg *AppConfig) ([]float32, error) {
+	embed := func(client *genai.Client) (*genai.EmbedContentResponse, error) {
+		llmCtx, cancel := context.WithTimeout(ctx, cfg.LLMTimeout)
+		defer cancel()
+		return client.EmbeddingModel(modelName).EmbedContent(llmCtx, genai.Text(text))
+	}
+
+	var resp *genai.EmbedContentResponse
+	_, err := gemini.withKeyRotation(ctx, func(_ *genai.GenerativeModel, client *genai.Client) error {
+		var err error
+		resp, err = embed(client)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("gemini EmbedContent failed: %w", err)
+	}
+	if resp.Embedding == nil || len(resp.Embedding.Values) == 0 {
+		return nil, fmt.Errorf("gemini returned an empty embedding")
+	}
+	return resp.Embedding.Values, nil
+}
+
+// embedSummaries embeds every successful summary, at most
+// cfg.ConcurrencyLimit at a time, and returns the vectors keyed by video ID.
+// Videos whose embedding fails are logged and left out.
+func embedSummaries(ctx context.Context, gemini *rotatingGeminiModel, videos []VideoDetails, allResults map[string]ProcessingResult, cfg *AppConfig) map[string][]float32 {
+	var mu sync.Mutex
+	vectors := make(map[string][]float32)
+	semaphore := make(chan struct{}, cfg.ConcurrencyLimit)
+	var wg sync.WaitGroup
+	for _, video := range videos {
+		result, ok := allResults[video.ID]
+		if !ok || result.Err != nil || result.Summary == "" {
+			continue
+		}
+		wg.Add(1)
+		go func(video VideoDetails, summary string) {
+			defer wg.Done()
+			select {
+			case semaphore <- struct{}{}:
+				defer func() { <-semaphore }()
+			case <-ctx.Done():
+				return
+			}
+			values, err := embedText(ctx, gemini, cfg.EmbeddingModel, summary, cfg)
+			if err != nil {
+				log.Printf("Video %s (%s): Failed to embed summary: %v", video.ID, video.Title, err)
+				return
+			}
+			mu.Lock()
+			vectors[video.ID] = values
+			mu.Unlock()
+		}(video, result.Summary)
+	}
+	wg.Wait()
+	return vectors
+}
+
+// writeSummaryEmbeddings writes one JSON object per embedded video to path,
+// in playlist order.
+func writeSummaryEmbeddings(path string, videos []VideoDetails, vectors map[string][]float32, cfg *AppConfig) (int, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, filePerm(cfg.TempPerms))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create embeddings file %s: %w", path, err)
+	}
+
+	encoder := json.NewEncoder(file)
+	written := 0
+	for _, video := range videos {
+		values, ok := vectors[video.ID]
+		if !ok {
+			continue
+		}
+		if err := encoder.Encode(summaryEmbedding{VideoID: video.ID, Title: video.Title, Model: cfg.EmbeddingModel, Embedding: values}); err != nil {
+			file.Close()
+			return written, fmt.Errorf("failed to write embeddings file %s: %w", path, err)
+		}
+		written++
+	}
+	if err := file.Close(); err != nil {
+		return written, fmt.Errorf("failed to write embeddings file %s: %w", path, err)
+	}
+	return written, nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+)
+
+// --- Explain Mode ---
+
+// explainTrail records the decisions made for one video under -explain, to
+// be logged as a single block when the video is done. A nil trail records
+// nothing, so call sites need not check whether -explain is set.
+type explainTrail struct {
+	mu    sync.Mutex
+	steps [][2]string // Key and value, in the order they were noted
+}
+
+type explainContextKey struct{}
+
+// withExplainTrail attaches trail to ctx; a nil trail leaves ctx unchanged.
+func withExplainTrail(ctx context.Context, trail *explainTrail) context.Context {
+	if trail == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, explainContextKey{}, trail)
+}
+
+// explainFrom returns the trail attached to ctx, or nil.
+func explainFrom(ctx context.Context) *explainTrail {
+	trail, _ := ctx.Value(explainContextKey{}).(*explainTrail)
+	return trail
+}
+
+// note records one decision. It is safe to call from the goroutines that
+// -compare-models starts for a video.
+func (t *explainTrail) note(key, format string, args ...any) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.steps = append(t.steps, [2]string{key, fmt.Sprintf(format, args...)})
+}
+
+// print logs the trail as one block, so that it is not interleaved with the
+// log lines of other workers.
+func (t *explainTrail) print(video VideoDetails) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var block strings.Builder
+	fmt.Fprintf(&block, "--- Explain: Video %s (%s) ---", video.ID, video.Title)
+	for _, step := range t.steps {
+		fmt.Fprintf(&block, "\n  %-20s %s", step[0]+":", step[1])
+	}
+	log.Print(block.String())
+}
+
+// ytDlpExitStatus describes how a yt-dlp run ended, for the trail.
+func ytDlpExitStatus(err error) string {
+	if err == nil {
+		return "exited cleanly"
+	}
+	return "exited with " + err.Error()
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// --- Vector Database Export ---
+
+const (
+	exportQdrant   = "qdrant"
+	exportPinecone = "pinecone"
+	exportWeaviate = "weaviate"
+
+	weaviateClassName = "VideoSummary"
+)
+
+var exportFormats = []string{exportQdrant, exportPinecone, exportWeaviate}
+
+func isExportFormat(format string) bool {
+	for _, known := range exportFormats {
+		if format == known {
+			return true
+		}
+	}
+	return false
+}
+
+// summaryPayload is the metadata attached to each exported record.
+type summaryPayload struct {
+	VideoID        string `json:"video_id"`
+	Title          string `json:"title"`
+	GeneratedTitle string `json:"generated_title,omitempty"`
+	Summary        string `json:"summary"`
+	URL            string `json:"url"`
+}
+
+type qdrantPoint struct {
+	ID      string         `json:"id"`
+	Vector  []float32      `json:"vector,omitempty"`
+	Payload summaryPayload `json:"payload"`
+}
+
+type pineconeVector struct {
+	ID       string         `json:"id"`
+	Values   []float32      `json:"values,omitempty"`
+	Metadata summaryPayload `json:"metadata"`
+}
+
+type weaviateObject struct {
+	Class      string         `json:"class"`
+	ID         string         `json:"id"`
+	Vector     []float32      `json:"vector,omitempty"`
+	Properties summaryPayload `json:"properties"`
+}
+
+// videoUUID derives a stable name-based (version 5 style) UUID from a video
+// ID, since Qdrant and Weaviate only accept UUIDs as record IDs.
+func videoUUID(videoID string) string {
+	sum := sha1.Sum([]byte("youtube:" + videoID))
+	sum[6] = sum[6]&0x0f | 0x50
+	sum[8] = sum[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+// exportRecord shapes one summary the way the given vector database ingests
+// it. vector may be nil, in which case it is omitted.
+func exportRecord(format string, video VideoDetails, generatedTitle, summary string, vector []float32) any {
+	payload := summaryPayload{
+		VideoID:        video.ID,
+		Title:          video.Title,
+		GeneratedTitle: generatedTitle,
+		Summary:        summary,
+		URL:            "https://www.youtube.com/watch?v=" + video.ID,
+	}
+	switch format {
+	case exportPinecone:
+		return pineconeVector{ID: video.ID, Values: vector, Metadata: payload}
+	case exportWeaviate:
+		return weaviateObject{Class: weaviateClassName, ID: videoUUID(video.ID), Vector: vector, Properties: payload}
+	default:
+		return qdrantPoint{ID: videoUUID(video.ID), Vector: vector, Payload: payload}
+	}
+}
+
+// writeVectorExport writes one NDJSON record per successful summary, in
+// playlist order. Vectors come from -embeddings and are omitted without it.
+func writeVectorExport(path, format string, videos []VideoDetails, allResults map[string]ProcessingResult, vectors map[string][]float32, perm os.FileMode) (int, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, filePerm(perm))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create export file %s: %w", path, err)
+	}
+
+	encoder := json.NewEncoder(file)
+	written := 0
+	for _, video := range videos {
+		result, ok := allResults[video.ID]
+		if !ok || result.Summary == "" {
+			continue
+		}
+		if err := encoder.Encode(exportRecord(format, video, result.GeneratedTitle, result.Summary, vectors[video.ID])); err != nil {
+			file.Close()
+			return written, fmt.Errorf("failed to write export file %s: %w", path, err)
+		}
+		written++
+	}
+	if err := file.Close(); err != nil {
+		return written, fmt.Errorf("failed to write export file %s: %w", path, err)
+	}
+	return written, nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// --- Per-Video Facts ---
+
+// videoFacts collects what is learned about a video while processing it,
+// such as the subtitle track the transcript source used or the summary
+// prompts sent to Gemini, for the worker to record on the video's result. A
+// nil *videoFacts records nothing.
+type videoFacts struct {
+	mu           sync.Mutex
+	subtitleLang string            // Language tag of the subtitle file used, e.g. "en" or "de-orig"
+	plainText    string            // Cue text before -cite, -preserve-speakers or -compact formatting; "" when unformatted
+	promptHashes map[string]string // Model name to the SHA-256 of the summary prompt sent to it, for -manifest
+	rateLimited  bool              // A Google API call was backed off after an HTTP 429
+}
+
+type videoFactsContextKey struct{}
+
+type summaryCallContextKey struct{}
+
+// withVideoFacts attaches facts to ctx.
+func withVideoFacts(ctx context.Context, facts *videoFacts) context.Context {
+	return context.WithValue(ctx, videoFactsContextKey{}, facts)
+}
+
+// videoFactsFrom returns the facts attached to ctx, or nil.
+func videoFactsFrom(ctx context.Context) *videoFacts {
+	facts, _ := ctx.Value(videoFactsContextKey{}).(*videoFacts)
+	return facts
+}
+
+// withSummaryCall marks ctx as carrying the video's summary requests, whose
+// prompts generateWithGeminiSchema records in the video's facts. Other
+// requests (verification, titles, comments) are not recorded.
+func withSummaryCall(ctx context.Context) context.Context {
+	return context.WithValue(ctx, summaryCallContextKey{}, true)
+}
+
+// isSummaryCall reports whether ctx was marked by withSummaryCall.
+func isSummaryCall(ctx context.Context) bool {
+	marked, _ := ctx.Value(summaryCallContextKey{}).(bool)
+	return marked
+}
+
+// recordPrompt records the SHA-256 of the exact prompt sent to model.
+func (f *videoFacts) recordPrompt(model, prompt string) {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.promptHashes == nil {
+		f.promptHashes = make(map[string]string)
+	}
+	f.promptHashes[model] = sha256Hex(prompt)
+}
+
+// promptHash returns the recorded prompt SHA-256 for model, or "" when no
+// summary prompt was sent to it.
+func (f *videoFacts) promptHash(model string) string {
+	if f == nil {
+		return ""
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.promptHashes[model]
+}
+
+// noteRateLimit records that a Google API call hit a rate limit.
+func (f *videoFacts) noteRateLimit() {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rateLimited = true
+}
+
+// hitRateLimit reports whether noteRateLimit was called.
+func (f *videoFacts) hitRateLimit() bool {
+	if f == nil {
+		return false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.rateLimited
+}
+
+// setSubtitleLanguage records the language tag of the subtitle file used.
+func (f *videoFacts) setSubtitleLanguage(lang string) {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.subtitleLang = lang
+}
+
+// setPlainTranscript records the plain cue text of a transcript that the
+// source returns reformatted.
+func (f *videoFacts) setPlainTranscript(text string) {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.plainText = text
+}
+
+// plainTranscript returns the recorded plain cue text, or "" when the
+// transcript was returned as plain text.
+func (f *videoFacts) plainTranscript() string {
+	if f == nil {
+		return ""
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.plainText
+}
+
+// subtitleLanguage returns the recorded subtitle language tag, or "" when
+// the transcript did not come from a subtitle file with one.
+func (f *videoFacts) subtitleLanguage() string {
+	if f == nil {
+		return ""
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.subtitleLang
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// --- Grouped Output ---
+
+// Values accepted by -group-by.
+const (
+	groupByPlaylist = "playlist"
+	groupByLanguage = "language"
+	groupByStatus   = "status"
+)
+
+// languageNames labels common language codes in grouped output; others are
+// shown as the code itself.
+var languageNames = map[string]string{
+	"en": "English",
+	"fr": "French",
+	"es": "Spanish",
+	"de": "German",
+	"it": "Italian",
+	"pt": "Portuguese",
+	"nl": "Dutch",
+}
+
+// videoGroup is one section of grouped output. Name is "" when output is
+// not grouped.
+type videoGroup struct {
+	Name   string
+	Videos []VideoDetails
+}
+
+func validateGroupBy(by string) error {
+	switch by {
+	case "", groupByPlaylist, groupByLanguage, groupByStatus:
+		return nil
+	default:
+		return fmt.Errorf("invalid -group-by %q: must be %s, %s or %s", by, groupByPlaylist, groupByLanguage, groupByStatus)
+	}
+}
+
+// groupVideos buckets videos by the -group-by dimension. Groups are ordered
+// by their first video, and videos keep their order within a group.
+func groupVideos(videos []VideoDetails, allResults map[string]ProcessingResult, cfg *AppConfig, titles *playlistTitleCache) []videoGroup {
+	if cfg.GroupBy == "" {
+		return []videoGroup{{Videos: videos}}
+	}
+	var groups []videoGroup
+	index := make(map[string]int)
+	for _, video := range videos {
+		name := groupName(video, allResults, cfg, titles)
+		i, ok := index[name]
+		if !ok {
+			i = len(groups)
+			index[name] = i
+			groups = append(groups, videoGroup{Name: name})
+		}
+		groups[i].Videos = append(groups[i].Videos, video)
+	}
+	return groups
+}
+
+func groupName(video VideoDetails, allResults map[string]ProcessingResult, cfg *AppConfig, titles *playlistTitleCache) string {
+	result, ok := allResults[video.ID]
+	switch cfg.GroupBy {
+	case groupByPlaylist:
+		if video.SourceLabel != "" {
+			return video.SourceLabel
+		}
+		return describeSources(cfg, titles)
+	case groupByLanguage:
+		if name, known := languageNames[result.Language]; known {
+			return name
+		}
+		if result.Language != "" {
+			return result.Language
+		}
+		return "Unknown language"
+	default:
+		return resultStatus(result, ok)
+	}
+}
+
+// resultStatus names a video's outcome for -group-by status and
+// -stats-only. ok is false when the video has no result.
+func resultStatus(result ProcessingResult, ok bool) string {
+	switch {
+	case !ok:
+		return "Not processed"
+	case errors.Is(result.Err, errNoTranscript):
+		return "No transcript"
+	case result.Err != nil:
+		return "Errors"
+	case result.Summary != "":
+		return "Summarized"
+	case result.TranscriptPath != "":
+		return "Transcript saved"
+	default:
+		return "No summary"
+	}
+}
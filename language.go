@@ -0,0 +1,89 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// --- Transcript Language Detection ---
+
+// languageStopwords holds very common words of each language -language-prompts
+// can select by. They are counted in the transcript to guess its language.
+var languageStopwords = map[string][]string{
+	"en": {"the", "and", "is", "of", "to", "that", "it", "you", "this", "was", "for", "with", "are", "have", "what"},
+	"fr": {"le", "la", "les", "et", "est", "des", "une", "que", "pas", "pour", "dans", "qui", "sur", "avec", "vous"},
+	"es": {"el", "la", "los", "las", "que", "es", "una", "por", "para", "con", "del", "pero", "como", "muy", "esto"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "ein", "eine", "ich", "mit", "auch", "auf", "sie", "wir", "aber"},
+	"it": {"il", "che", "di", "una", "sono", "per", "non", "della", "questo", "anche", "come", "gli", "nel", "perché", "molto"},
+	"pt": {"o", "que", "não", "uma", "para", "com", "os", "mas", "como", "isso", "muito", "você", "está", "também", "ele"},
+	"nl": {"de", "het", "een", "en", "is", "niet", "dat", "van", "ik", "je", "maar", "ook", "wat", "zijn", "voor"},
+}
+
+const (
+	languageSampleWords = 2000 // Words of the transcript inspected
+	languageMinShare    = 0.05 // Minimum share of stopword hits among the sampled words
+)
+
+// baseLanguage reduces a subtitle language tag to its language code:
+// "de-orig", "de-DE" and "de" all become "de".
+func baseLanguage(tag string) string {
+	code, _, _ := strings.Cut(tag, "-")
+	return strings.ToLower(code)
+}
+
+// transcriptLanguage returns the transcript's language code. For subtitles
+// that is the language of the track yt-dlp downloaded (track, e.g.
+// "de-orig"); other sources report no track, so the language is detected
+// from the text. It is "" when unknown.
+func transcriptLanguage(track, transcript string) string {
+	if track != "" {
+		return baseLanguage(track)
+	}
+	return detectLanguage(transcript)
+}
+
+// summaryLanguage returns the language a summary is written in: English,
+// or under -same-language the transcript's language (lang), which may be
+// "" when unknown.
+func summaryLanguage(lang string, cfg *AppConfig) string {
+	if !cfg.SameLanguage {
+		return "en"
+	}
+	return lang
+}
+
+// detectLanguage guesses the transcript's language from stopword frequencies
+// and returns its code (e.g. "fr"), or "" when no language stands out.
+func detectLanguage(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	if len(words) > languageSampleWords {
+		words = words[:languageSampleWords]
+	}
+	if len(words) == 0 {
+		return ""
+	}
+
+	counts := make(map[string]int)
+	for _, word := range words {
+		counts[word]++
+	}
+	best, bestScore, runnerUp := "", 0, 0
+	for lang, stopwords := range languageStopwords {
+		score := 0
+		for _, stopword := range stopwords {
+			score += counts[stopword]
+		}
+		switch {
+		case score > bestScore:
+			best, bestScore, runnerUp = lang, score, bestScore
+		case score > runnerUp:
+			runnerUp = score
+		}
+	}
+	if float64(bestScore) < languageMinShare*float64(len(words)) || bestScore*2 < runnerUp*3 {
+		return ""
+	}
+	return best
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// --- Local Media Files ---
+
+// ffmpegCommand extracts embedded subtitle tracks from -local-file media.
+var ffmpegCommand = "ffmpeg"
+
+// localFileVideo describes a -local-file input as a video so it can go
+// through the usual summarize and report steps. The ID is the file name
+// without its extension.
+func localFileVideo(path string) VideoDetails {
+	name := filepath.Base(path)
+	return VideoDetails{
+		ID:          strings.TrimSuffix(name, filepath.Ext(name)),
+		Title:       name,
+		Source:      "local-file",
+		SourceLabel: "local file " + path,
+	}
+}
+
+// localFileTranscriptSource reads the first embedded subtitle track of a
+// local video or audio file with ffmpeg. It never talks to YouTube.
+type localFileTranscriptSource struct {
+	path string
+	cfg  *AppConfig
+}
+
+func (s localFileTranscriptSource) Fetch(ctx context.Context, video VideoDetails) (string, int, error) {
+	if err := makeDir(s.cfg.TempTranscriptDir, s.cfg.TempPerms); err != nil {
+		return "", 1, fmt.Errorf("failed to create temp dir %s: %w", s.cfg.TempTranscriptDir, err)
+	}
+	vttPath := filepath.Join(s.cfg.TempTranscriptDir, video.ID+".local.vtt")
+	args := []string{"-y", "-v", "error", "-i", s.path, "-map", "0:s:0", "-f", "webvtt", vttPath}
+	log.Printf("Video %s: Extracting embedded subtitles: %s %s", video.ID, ffmpegCommand, strings.Join(args, " "))
+	output, err := exec.CommandContext(ctx, ffmpegCommand, args...).CombinedOutput()
+	if err != nil {
+		if strings.Contains(string(output), "matches no streams") {
+			log.Printf("Video %s: %s has no embedded subtitle track; no captions are available.", video.ID, s.path)
+			return "", 1, nil
+		}
+		return "", 1, fmt.Errorf("%s failed on %s: %w (output: %s)", ffmpegCommand, s.path, err, strings.TrimSpace(string(output)))
+	}
+	if s.cfg.NoCleanup {
+		log.Printf("Video %s: Keeping subtitle file %s (-no-cleanup).", video.ID, vttPath)
+	} else {
+		defer os.Remove(vttPath)
+	}
+
+	if streamingSubtitles(s.cfg) {
+		transcript, _, err := streamPlainTranscript(vttPath, s.cfg.TranscriptJoin)
+		if err != nil {
+			return "", 1, fmt.Errorf("failed to read subtitles extracted from %s: %w", s.path, err)
+		}
+		return transcript, 1, nil
+	}
+	subs, err := openSubtitles(vttPath, s.cfg.StrictParse)
+	if err != nil {
+		return "", 1, fmt.Errorf("failed to parse subtitles extracted from %s: %w", s.path, err)
+	}
+	if s.cfg.Cite || s.cfg.PreserveSpeakers {
+		videoFactsFrom(ctx).setPlainTranscript(buildPlainTranscript(subs, s.cfg.TranscriptJoin))
+	}
+	if s.cfg.Cite {
+		return buildTimestampedTranscript(subs), 1, nil
+	}
+	if s.cfg.PreserveSpeakers {
+		return buildSpeakerTranscript(subs), 1, nil
+	}
+	return buildPlainTranscript(subs, s.cfg.TranscriptJoin), 1, nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+)
+
+// --- Log File ---
+
+// setupLogFile points the standard logger at cfg.LogFile, also keeping stderr
+// unless -log-to-stderr=false. The file is truncated each run unless
+// -log-append is set. The returned function closes the file; run defers it,
+// so it also runs after an interrupted run has wound down.
+func setupLogFile(cfg *AppConfig) (func(), error) {
+	if cfg.LogFile == "" {
+		return func() {}, nil
+	}
+	mode := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if cfg.LogAppend {
+		mode = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	file, err := os.OpenFile(cfg.LogFile, mode, filePerm(cfg.TempPerms))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log file %s: %w", cfg.LogFile, err)
+	}
+	if cfg.LogToStderr {
+		log.SetOutput(io.MultiWriter(os.Stderr, file))
+	} else {
+		log.SetOutput(file)
+	}
+
+	closeFile := func() {
+		log.SetOutput(os.Stderr)
+		file.Sync()
+		file.Close()
+	}
+	return closeFile, nil
+}
@@ -2,18 +2,32 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
+	"net"
+	"net/http"
 	"os"
-	"os/exec"
+	"os/signal"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 	"sync"
+	"syscall"
+	"text/template"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/asticode/go-astisub"
 	"github.com/google/generative-ai-go/genai"
 	"github.com/joho/godotenv"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/googleapi/transport"
+	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 	"google.golang.org/api/youtube/v3"
 )
@@ -23,45 +37,245 @@ const (
 	defaultPlaylistID           = "PL8GTokWa3GEeH8kUkx0rzRWwrzlvO8JaT"
 	defaultGeminiModel          = "gemini-1.5-flash-latest"
 	defaultTempTranscriptDir    = "./transcripts_temp"
+	defaultKeepTranscriptsDir   = "./transcripts"
+	storedTranscriptExt         = ".txt"
+	storedTranscriptIndexFile   = "titles.tsv"
+	transcriptIndexMarkdown     = "index.md"
 	defaultMaxTranscriptRetries = 3
 	defaultTranscriptRetryDelay = 5 * time.Second
 	defaultLLMTimeout           = 60 * time.Second
+	defaultModelInfoTimeout     = 15 * time.Second
+	defaultHTTPTimeout          = 90 * time.Second
+	defaultMaxRetryAfter        = time.Minute
+	maxModelSuggestions         = 5
+	exitCodeRunTimeout          = 3
+	exitCodeStoppedOnError      = 4
+	exitCodeInterrupted         = 130 // As a shell reports a process ended by SIGINT
+	maxCombinedDescriptionRunes = 5000
+	charsPerTokenEstimate       = 4
+	combinedDescriptionFormat   = "[Video description, written by the creator]\n%s\n[End of description]\n\n[Spoken transcript]\n%s"
+	maxContextFileRunes         = 4000
+	contextPromptFormat         = "Use the following background notes (a glossary or context supplied by the user) to interpret names and jargon in the transcript. Do not summarize the notes themselves.\n[Background notes]\n%s\n[End of background notes]\n\n"
+	transcriptJoinSpace         = "space"
+	transcriptJoinNewline       = "newline"
+	compactParagraphGap         = 2 * time.Second
 	defaultConcurrencyLimit     = 5
 	defaultSummaryWordCount     = 15
+	defaultConfirmThreshold     = 100
+	defaultCaptionWaitRetries   = 3
+	captionWaitMaxAge           = 24 * time.Hour // Only uploads newer than this wait for captions
 	summaryPromptFormat         = "Summarize this video transcript in exactly %d words:\n\nTranscript:\n\"%s\""
+	sentencePromptFormat        = "Summarize this video transcript in exactly %d sentences:\n\nTranscript:\n\"%s\""
+	chapterPromptFormat         = "\n\nThe video is divided into these chapters (from its description); use them to structure the summary:\n%s"
+	sameLanguagePrompt          = "\n\nWrite the summary in the same language as the transcript, not in English unless the transcript is in English."
 	envYoutubeAPIKey            = "YOUTUBE_API_KEY"
+	envYoutubeAPIKeys           = "YOUTUBE_API_KEYS"
 	envGeminiAPIKey             = "GEMINI_API_KEY"
+	envGeminiAPIKeys            = "GEMINI_API_KEYS"
 	envPlaylistID               = "PLAYLIST_ID"
 	envGeminiModel              = "GEMINI_MODEL"
 )
 
+// geminiModelAliases maps the short names accepted by -model and GEMINI_MODEL
+// to full model IDs. Keep this the only place aliases are defined so it is
+// easy to update when Google renames models.
+var geminiModelAliases = map[string]string{
+	"flash":    "gemini-1.5-flash-latest",
+	"flash-8b": "gemini-1.5-flash-8b-latest",
+	"pro":      "gemini-1.5-pro-latest",
+	"flash-2":  "gemini-2.0-flash",
+}
+
 // AppConfig (from previous step - unchanged)
 type AppConfig struct {
-	YoutubeAPIKey        string
-	GeminiAPIKey         string
+	YoutubeAPIKeys       []string
+	GeminiAPIKeys        []string
 	PlaylistID           string
 	GeminiModel          string
 	TempTranscriptDir    string
 	MaxTranscriptRetries int
 	TranscriptRetryDelay time.Duration
 	LLMTimeout           time.Duration
+	HTTPTimeout          time.Duration
 	ConcurrencyLimit     int
 	SummaryWordCount     int
+	SummarySentences     int // When positive, the summary length is requested in sentences instead of words
+	CompactTranscript    bool
+	PreviewWords         int
+	UseChapters          bool
+	NoCleanup            bool
+	KeepTranscriptsDir   string
+	TranscriptsOnly      bool
+	IncludeComments      int
+	RunTimeout           time.Duration
+	FromTranscriptsDir   string
+	TranscriptJoin       string
+	ThumbnailsDir        string
+	Strict               bool
+	DedupeThreshold      float64
+	AddedSince           time.Time // Zero means no playlist-addition filter
+	ConfirmThreshold     int
+	AssumeYes            bool
+	EmbeddingsPath       string
+	EmbeddingModel       string
+	YtDlpUserAgent       string
+	YtDlpHeaders         stringListFlag // "Name:Value" pairs passed to yt-dlp --add-header
+	Playlists            []string       // -playlists; when any source flag is set PlaylistID is ignored
+	Channels             []string       // Channel IDs or @handles whose uploads are summarized
+	VideoIDs             stringListFlag
+	InputFile            string
+	CaptionWait          time.Duration
+	CaptionWaitRetries   int
+	YtDlpProxies         *proxyRotation // nil when -proxy is not set
+	SameLanguage         bool
+	PlaylistPrompts      map[string]string // Playlist ID to prompt template, from -playlist-prompts
+	LanguagePrompts      map[string]string // Language code to prompt template, from -language-prompts
+	ModelPrompts         map[string]string // Gemini model ID to prompt template, from -model-prompts
+	NoAutoTranslate      bool
+	UntilVideoID         string
+	ExportFormat         string // qdrant, pinecone or weaviate; empty disables -export
+	ExportPath           string
+	CSVPath              string
+	CSVColumns           []csvColumn
+	TranscriptSource     string // -transcript-source; empty picks the default for the mode
+	Doctor               bool
+	StopOnFirstError     bool
+	CombineDescription   bool
+	RetryEmptyTranscript bool
+	LogFile              string
+	LogToStderr          bool
+	LogAppend            bool
+	LocalFile            string
+	Usage                *usageTracker // Gemini calls, tokens and characters sent; enforces -max-cost and -max-total-chars
+	MaxCostStopsFetching bool
+	CompareModels        []string
+	AppendJSONL          string
+	AppendJSONLFsync     bool
+	Bare                 bool
+	Estimate             bool
+	GroupBy              string
+	ContextText          string    // Contents of -context-file, capped at maxContextFileRunes
+	Redactor             *redactor // nil unless -redact is set
+	OTelEndpoint         string
+	MinConcurrency       int
+	NotesDir             string
+	NotesNoTranscript    bool
+	StrictParse          bool
+	Color                bool
+	NoColor              bool
+	Order                string
+	MaxRetryAfter        time.Duration
+	Cite                 bool
+	OrderedStream        bool
+	TempPerms            os.FileMode
+	PreserveSpeakers     bool
+	CleanupOnStart       bool
+	StatsOnly            bool
+	SkipSponsors         bool
+	TitleMatch           *regexp.Regexp // nil unless -title-match is set
+	TitleExclude         *regexp.Regexp // nil unless -title-exclude is set
+	Retitle              bool
+	RetitleFileNames     bool
+	PositionRange        positionRange
+	EmptyPlaceholder     string
+	Audience             string
+	WarningsJSON         string
+	Explain              bool
+	PublishURL           string
+	PublishTopic         string
+	PublishBuffer        int
+	MinQuality           float64
+	LowQualityAction     string
+	PauseFile            string
+	Sections             bool
+	SectionHeaders       []string
+	RetryOn              []string // Lower-cased -retry-on substrings; nil uses the built-in classification
+	ManifestPath         string
+	Structured           bool
+	StreamSubtitles      bool
+	ChannelLimit         int
+	SeriesContext        bool
+	PreviousSummary      string // Set on a per-video copy under -series-context: the summary carried over from the previous video
+	Verify               bool
+	VerifyMaxChars       int
+	OutputRate           float64
+	OutputBurst          int
+	SummaryPrefix        *template.Template // -summary-prefix; nil when unset
+	SummarySuffix        *template.Template // -summary-suffix; nil when unset
+}
+
+// errSkipped wraps every result error that describes a video with nothing to
+// summarize, or one the run chose not to summarize, rather than a failure;
+// -stop-on-first-error ignores them.
+var errSkipped = errors.New("skipped")
+
+var (
+	errNoTranscript          = fmt.Errorf("%w: no transcript available", errSkipped)
+	errSummarizerUnavailable = fmt.Errorf("%w: Gemini client not available", errSkipped)
+	errBudgetExceeded        = fmt.Errorf("%w: -max-cost budget reached", errSkipped)
+	errCharBudgetExhausted   = fmt.Errorf("%w: -max-total-chars budget reached", errSkipped)
+	errLowQualityTranscript  = fmt.Errorf("%w: transcript quality below -min-quality", errSkipped)
+)
+
+var errStoppedOnFirstError = errors.New("stopped after the first error (-stop-on-first-error)")
+
+// stringListFlag collects the values of a flag that may be repeated.
+type stringListFlag []string
+
+func (f *stringListFlag) String() string { return strings.Join(*f, ", ") }
+
+func (f *stringListFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
 }
 
 // --- Data Structures ---
 
 // VideoDetails contains essential information about a YouTube video.
 type VideoDetails struct { // Renamed from VideoInfo
-	ID    string
-	Title string
+	ID           string
+	Title        string
+	Description  string
+	ThumbnailURL string
+	AddedAt      time.Time // When the video was added to the playlist, not when it was published
+	Source       string    // Which -playlists/-channel/-video/-input-file source listed it; empty for PLAYLIST_ID
+	SourceLabel  string    // Human-readable Source, using the playlist title when known
+	PublishedAt  time.Time // When the video itself was published
+	WordCount    int       // Per-video summary length from -input-file; 0 uses -words
+	Position     int64     // Position in its playlist counted from 1, as YouTube shows it; 0 for other sources
 }
 
 // ProcessingResult holds the outcome of fetching and summarizing a video transcript.
 type ProcessingResult struct { // Renamed from SummaryInfo
-	VideoDetails VideoDetails // Embed VideoDetails
-	Summary      string
-	Err          error // Changed from string to error type
+	VideoDetails        VideoDetails // Embed VideoDetails
+	Summary             string
+	Chapters            []Chapter      // Parsed from the description when -use-chapters is set
+	TranscriptPath      string         // Where the transcript was saved when -keep-transcripts is set
+	CommentsSummary     string         // Audience sentiment from top comments when -include-comments is set
+	ThumbnailPath       string         // Local thumbnail file when -thumbnails is set
+	TranscriptAttempts  int            // yt-dlp runs made for the video, including retries
+	LLMAttempts         int            // Summary requests made, including retries after key rotation
+	RateLimited         bool           // A Google API call hit an HTTP 429 rate limit while processing the video
+	Truncated           bool           // Gemini hit its output token limit, so the summary may end mid-sentence
+	DescriptionIncluded bool           // The description was sent along with the transcript (-combine-description)
+	ModelSummaries      []ModelSummary // One per model, in -compare-models order
+	EstimatedTokens     int            // Approximate prompt size under -estimate
+	Language            string         // Detected transcript language code under -group-by language
+	NotesPath           string         // Markdown study notes written with -notes
+	Citations           int            // (MM:SS) citations kept in the summary under -cite
+	TranscriptChars     int            // Length of the fetched transcript, in characters
+	Elapsed             time.Duration  // Time the worker spent on the video
+	GeneratedTitle      string         // Descriptive title written by Gemini under -retitle; VideoDetails.Title keeps the original
+	Quality             *qualityScores // Measured under -min-quality and -explain; nil otherwise
+	LowQuality          bool           // Quality was below -min-quality and -low-quality is flag
+	Sections            []Section      // The summary split at the -sections headers; nil if they were missing
+	Manifest            *manifestEntry // Checksums and timestamps for -manifest; nil otherwise
+	Structured          *Extract       // The -structured summary; Summary holds it rendered as text
+	ContextFrom         string         // Video whose summary was given as -series-context
+	UnsupportedClaims   []string       // Claims -verify found unsupported by the transcript; nil if not verified
+	SummaryLanguage     string         // Language code the summary was asked for: "en", or the transcript's under -same-language
+	SubtitleTrack       string         // Language tag of the subtitle file used, e.g. "en" or "de-orig"; "" for other sources
+	Err                 error          // Changed from string to error type
 }
 
 // --- Initialization and Setup --- (Unchanged from previous step)
@@ -72,6 +286,16 @@ func loadEnvironmentFile() {
 	}
 }
 
+// resolveGeminiModel expands a model alias to its full ID. Names that are not
+// aliases are returned unchanged.
+func resolveGeminiModel(name string) string {
+	if fullName, ok := geminiModelAliases[strings.ToLower(strings.TrimSpace(name))]; ok {
+		log.Printf("Resolved Gemini model alias %q to %s.", name, fullName)
+		return fullName
+	}
+	return name
+}
+
 func getEnvWithDefault(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -81,27 +305,507 @@ func getEnvWithDefault(key, defaultValue string) string {
 
 func initializeAppConfig() (*AppConfig, error) {
 	cfg := &AppConfig{
-		YoutubeAPIKey:        os.Getenv(envYoutubeAPIKey),
-		GeminiAPIKey:         os.Getenv(envGeminiAPIKey),
+		YoutubeAPIKeys:       getEnvKeyList(envYoutubeAPIKeys, envYoutubeAPIKey),
+		GeminiAPIKeys:        getEnvKeyList(envGeminiAPIKeys, envGeminiAPIKey),
 		PlaylistID:           getEnvWithDefault(envPlaylistID, defaultPlaylistID),
 		GeminiModel:          getEnvWithDefault(envGeminiModel, defaultGeminiModel),
 		TempTranscriptDir:    defaultTempTranscriptDir,
 		MaxTranscriptRetries: defaultMaxTranscriptRetries,
 		TranscriptRetryDelay: defaultTranscriptRetryDelay,
 		LLMTimeout:           defaultLLMTimeout,
+		HTTPTimeout:          defaultHTTPTimeout,
 		ConcurrencyLimit:     defaultConcurrencyLimit,
+		MinConcurrency:       1,
 		SummaryWordCount:     defaultSummaryWordCount,
 	}
-	if cfg.YoutubeAPIKey == "" {
-		return nil, fmt.Errorf("%s environment variable must be set", envYoutubeAPIKey)
+
+	flag.IntVar(&cfg.SummaryWordCount, "words", defaultSummaryWordCount, "Number of words to request for each summary")
+	flag.IntVar(&cfg.SummarySentences, "sentences", 0, "Request each summary as exactly N sentences instead of a word count (cannot be combined with -words)")
+	flag.StringVar(&cfg.GeminiModel, "model", cfg.GeminiModel, "Gemini model ID or alias (flash, flash-8b, pro, flash-2); overrides "+envGeminiModel)
+	flag.BoolVar(&cfg.CompactTranscript, "compact", false, "Merge caption cues into paragraphs and drop rolling-caption overlap before summarizing")
+	flag.DurationVar(&cfg.HTTPTimeout, "http-timeout", defaultHTTPTimeout, "Transport-level timeout for YouTube and Gemini HTTP requests (0 uses the library defaults)")
+	flag.IntVar(&cfg.PreviewWords, "preview", 0, "Print only the first N words of each summary in the console report (0 prints the full summary)")
+	flag.BoolVar(&cfg.UseChapters, "use-chapters", false, "Parse timestamped chapters from each video description and include them in the prompt")
+	flag.BoolVar(&cfg.NoCleanup, "no-cleanup", false, "Keep downloaded subtitle files and the temp directory for debugging")
+	flag.StringVar(&cfg.KeepTranscriptsDir, "keep-transcripts", "", "Save each fetched transcript as <dir>/<videoID>.txt")
+	flag.BoolVar(&cfg.TranscriptsOnly, "transcripts-only", false, "Only fetch and save transcripts, skipping summarization (saves to "+defaultKeepTranscriptsDir+" unless -keep-transcripts is set)")
+	flag.IntVar(&cfg.IncludeComments, "include-comments", 0, "Fetch the top N comments of each video and summarize the audience sentiment (uses extra YouTube quota)")
+	flag.DurationVar(&cfg.RunTimeout, "run-timeout", 0, "Hard cap on total runtime; when reached, no new videos are started and completed results are reported (0 disables)")
+	flag.StringVar(&cfg.FromTranscriptsDir, "from-transcripts", "", "Summarize <videoID>.txt transcripts from this directory instead of fetching from YouTube")
+	flag.StringVar(&cfg.TranscriptJoin, "transcript-join", transcriptJoinSpace, "How caption lines are joined in the transcript: space or newline (ignored with -compact)")
+	flag.StringVar(&cfg.ThumbnailsDir, "thumbnails", "", "Download each video's thumbnail to <dir>/<videoID>.jpg")
+	flag.BoolVar(&cfg.Strict, "strict", false, "Fail instead of falling back to the built-in playlist ID, model, and word count defaults")
+	flag.Float64Var(&cfg.DedupeThreshold, "dedupe-threshold", 0, "Report summaries at least this similar (0-1, cosine over word sets) as near-duplicates (0 disables)")
+	flag.IntVar(&cfg.ConfirmThreshold, "confirm-over", defaultConfirmThreshold, "Ask for confirmation on stdin before processing more than this many videos (0 disables)")
+	flag.BoolVar(&cfg.AssumeYes, "yes", false, "Skip the large-run confirmation prompt")
+	flag.StringVar(&cfg.EmbeddingsPath, "embeddings", "", "Embed each summary and write the vectors as NDJSON (one object per video) to this file")
+	flag.StringVar(&cfg.EmbeddingModel, "embedding-model", defaultEmbeddingModel, "Gemini embedding model used by -embeddings")
+	flag.StringVar(&cfg.YtDlpUserAgent, "user-agent", "", "User agent passed to yt-dlp (--user-agent)")
+	flag.Var(&cfg.YtDlpHeaders, "add-header", "Extra \"Name:Value\" HTTP header passed to yt-dlp (--add-header); may be repeated")
+	playlists := flag.String("playlists", "", "Comma-separated playlist IDs to summarize (overrides "+envPlaylistID+")")
+	channels := flag.String("channel", "", "Comma-separated channel IDs or @handles whose uploads are summarized")
+	flag.Var(&cfg.VideoIDs, "video", "Video ID or URL to summarize; may be repeated")
+	flag.StringVar(&cfg.InputFile, "input-file", "", "File with one video ID or URL per line to summarize")
+	flag.DurationVar(&cfg.CaptionWait, "caption-wait", 0, "For videos published in the last 24h with no captions yet, wait this long and retry (0 disables)")
+	flag.IntVar(&cfg.CaptionWaitRetries, "caption-wait-retries", defaultCaptionWaitRetries, "How many times -caption-wait retries a video before giving up")
+	flag.BoolVar(&cfg.SameLanguage, "same-language", false, "Ask Gemini to write each summary in the transcript's language instead of English")
+	playlistPrompts := flag.String("playlist-prompts", "", "JSON file mapping playlist IDs to prompt templates using {words} and {transcript}")
+	modelPrompts := flag.String("model-prompts", "", "JSON file mapping Gemini models (IDs or aliases) to the default prompt template used with that model")
+	languagePrompts := flag.String("language-prompts", "", "JSON file mapping language codes (e.g. en, fr, ja) to prompt templates for transcripts in that language")
+	flag.BoolVar(&cfg.NoAutoTranslate, "no-autotranslate", false, "Use the original-language captions instead of YouTube's machine-translated English ones")
+	flag.StringVar(&cfg.UntilVideoID, "until-id", "", "Stop listing each playlist or channel at this video ID (newest-first order), processing only newer videos")
+	flag.StringVar(&cfg.ExportFormat, "export", "", "Write summaries as vector-DB records: qdrant, pinecone or weaviate (vectors need -embeddings)")
+	summaryPrefix := flag.String("summary-prefix", "", "Text template added before every summary in all outputs, e.g. \"{{.Date}}: {{.Title}}\"; fields are .URL, .Title and .Date")
+	summarySuffix := flag.String("summary-suffix", "", "Text template added after every summary in all outputs, e.g. \"Watch: {{.URL}}\"; fields are .URL, .Title and .Date")
+	flag.Float64Var(&cfg.OutputRate, "output-rate", 0, "Deliver at most this many results per second to -publish-url, queueing the rest, so bursts do not overwhelm the receiver (0 is unlimited)")
+	flag.IntVar(&cfg.OutputBurst, "output-burst", 1, "Results -output-rate lets through back to back before pacing starts")
+	flag.BoolVar(&cfg.Verify, "verify", false, "After summarizing, make a second Gemini call that checks each claim in the summary against the transcript and lists unsupported ones in the report")
+	flag.IntVar(&cfg.VerifyMaxChars, "verify-max-chars", defaultVerifyMaxChars, "Skip -verify for transcripts longer than this many characters, to bound its cost (0 verifies every length)")
+	flag.BoolVar(&cfg.SeriesContext, "series-context", false, "Give each video's prompt the summary of the video before it in the same playlist or channel as already-covered context; videos are processed one at a time, in list order (see -order)")
+	flag.IntVar(&cfg.ChannelLimit, "channel-limit", 0, "Stop listing each -channel's uploads after this many videos, newest first, so large channels are not paged through in full (0 lists all)")
+	flag.BoolVar(&cfg.StreamSubtitles, "stream-subtitles", false, "Build plain transcripts while reading the subtitle file instead of parsing it into memory first, for very long videos; -cite, -preserve-speakers, -compact and -skip-sponsors still parse fully")
+	flag.BoolVar(&cfg.Structured, "structured", false, "Ask Gemini for each summary as a JSON object (title, one_liner, key_points, topics, sentiment) using a response schema; written to -append-jsonl and -publish-url records")
+	flag.StringVar(&cfg.ManifestPath, "manifest", "", "Write a JSON manifest of the run to this file: the effective config (credentials redacted) and, per video, the transcript source, SHA-256 checksums of the transcript, prompt and summary, and timestamps")
+	retryOn := flag.String("retry-on", "", "Comma-separated yt-dlp error substrings (case-insensitive) that make a failed fetch retryable; anything else fails fast (default: the built-in network errors, plus unrecognized failures)")
+	flag.BoolVar(&cfg.Sections, "sections", false, "Ask for each summary under fixed headers (see -section-headers), rendered as Markdown headers in -notes")
+	sectionHeaders := flag.String("section-headers", defaultSectionHeaders, "Comma-separated headers for -sections, in order")
+	flag.StringVar(&cfg.PauseFile, "pause-file", "", "While this file exists, start no new videos (those in progress finish); delete it to resume")
+	flag.Float64Var(&cfg.MinQuality, "min-quality", 0, "Transcript quality score (0-1, from punctuation, vocabulary and repetition) below which -low-quality applies (0 disables)")
+	flag.StringVar(&cfg.LowQualityAction, "low-quality", lowQualitySkip, "What to do with transcripts below -min-quality: skip them or flag their summaries in the report")
+	flag.StringVar(&cfg.PublishURL, "publish-url", os.Getenv(envPublishURL), "Publish each finished result as JSON to this broker, e.g. nats://localhost:4222; overrides "+envPublishURL)
+	flag.StringVar(&cfg.PublishTopic, "publish-topic", getEnvWithDefault(envPublishTopic, defaultPublishTopic), "Subject that -publish-url results are published to; overrides "+envPublishTopic)
+	flag.IntVar(&cfg.PublishBuffer, "publish-buffer", defaultPublishBuffer, "Results -publish-url holds in memory while the broker catches up before the run waits for it")
+	flag.BoolVar(&cfg.Explain, "explain", false, "Log a block per video with each decision made: subtitle tracks found and chosen, cleaning, prompt size, model, retries and finish reason")
+	flag.StringVar(&cfg.WarningsJSON, "warnings-json", "", "Write the run's warnings, tagged with category and video ID, to this file as a JSON array")
+	flag.StringVar(&cfg.Audience, "audience", "", "Tailor summaries' vocabulary and focus to engineers, executives, students or general readers")
+	flag.StringVar(&cfg.EmptyPlaceholder, "empty-placeholder", "", "Text written as the summary of videos without one in -append-jsonl, -csv and the -keep-transcripts index (e.g. \"[no transcript available]\")")
+	flag.Int64Var(&cfg.PositionRange.start, "position-start", 0, "Only process playlist and channel videos from this position on (1 is the first video; 0 starts at the beginning)")
+	flag.Int64Var(&cfg.PositionRange.end, "position-end", 0, "Only process playlist and channel videos up to this position, and stop listing past it (0 goes to the end)")
+	flag.BoolVar(&cfg.Retitle, "retitle", false, "After summarizing, ask Gemini for a concise, descriptive title, shown alongside the original")
+	flag.BoolVar(&cfg.RetitleFileNames, "retitle-filenames", false, "Name -notes files after the -retitle title instead of the original one")
+	maxTotalChars := flag.Int64("max-total-chars", 0, "Stop sending transcripts to Gemini once this many characters have been sent in the run (0 disables)")
+	flag.StringVar(&cfg.CSVPath, "csv", "", "Write one CSV row per video to this file")
+	csvColumnList := flag.String("csv-columns", defaultCSVColumns, "Comma-separated columns for -csv, in order (see README for the names)")
+	flag.StringVar(&cfg.ExportPath, "export-file", "", "File written by -export (default summify-<format>.ndjson)")
+	flag.StringVar(&cfg.TranscriptSource, "transcript-source", "", "Where transcripts come from: yt-dlp or files (default: files with -from-transcripts, yt-dlp otherwise)")
+	flag.BoolVar(&cfg.RetryEmptyTranscript, "retry-empty-transcript", false, "When the downloaded VTT parses to an empty transcript, fetch once more as SRT")
+	flag.BoolVar(&cfg.CombineDescription, "combine-description", false, "Send each video's description (up to 5000 characters) along with its transcript")
+	flag.BoolVar(&cfg.StopOnFirstError, "stop-on-first-error", false, "Cancel the run as soon as any video fails (missing transcripts do not count) and report what completed")
+	flag.BoolVar(&cfg.Cite, "cite", false, "Send a timestamped transcript and have the summary cite (MM:SS) where each key point is made")
+	flag.DurationVar(&cfg.MaxRetryAfter, "max-retry-after", defaultMaxRetryAfter, "Longest Retry-After delay to wait out once every API key is rate limited (0 fails right away)")
+	flag.StringVar(&cfg.Order, "order", "", "Process and report videos in playlist (position), oldest or newest (publish date) order (default: the order the sources list them)")
+	flag.BoolVar(&cfg.Color, "color", false, "Always color the report's status lines, even when stdout is not a terminal")
+	flag.BoolVar(&cfg.NoColor, "no-color", false, "Never color the report (colors are used only on terminals, and not when NO_COLOR is set)")
+	flag.BoolVar(&cfg.StrictParse, "strict-parse", false, "Fail on malformed subtitle files instead of falling back to lenient parsing")
+	flag.StringVar(&cfg.NotesDir, "notes", "", "Directory to write a Markdown study note per video: title, link, summary and the full transcript")
+	flag.BoolVar(&cfg.NotesNoTranscript, "notes-no-transcript", false, "Leave the transcript out of -notes files")
+	flag.IntVar(&cfg.ConcurrencyLimit, "max-concurrency", defaultConcurrencyLimit, "Videos processed at once; halved on rate-limit errors and ramped back up as calls succeed")
+	flag.IntVar(&cfg.MinConcurrency, "min-concurrency", 1, "Lowest concurrency rate-limit throttling may reduce to")
+	flag.StringVar(&cfg.OTelEndpoint, "otel-endpoint", "", "OTLP/HTTP collector (e.g. http://localhost:4318) to export run and per-video trace spans to")
+	redact := flag.Bool("redact", false, "Mask emails, phone numbers and card-like numbers in transcripts before they are sent to the LLM")
+	var redactPatterns stringListFlag
+	flag.Var(&redactPatterns, "redact-pattern", "Extra regular expression to mask with -redact (may be repeated)")
+	contextFile := flag.String("context-file", "", "Text file of background notes (e.g. a glossary) to include in every summary prompt, up to 4000 characters")
+	flag.StringVar(&cfg.GroupBy, "group-by", "", "Group the report and transcript index by playlist, language or status (default: one list in playlist order)")
+	flag.BoolVar(&cfg.Estimate, "estimate", false, "Fetch transcripts and report each video's estimated prompt tokens without calling Gemini")
+	flag.BoolVar(&cfg.Bare, "bare", false, "Print only the summaries on stdout, one per line; the rest of the report goes to stderr")
+	flag.StringVar(&cfg.AppendJSONL, "append-jsonl", "", "Append each finished video's result to this NDJSON file and skip videos it already records as done")
+	titleMatch := flag.String("title-match", "", "Only summarize videos whose title matches this regular expression")
+	titleExclude := flag.String("title-exclude", "", "Skip videos whose title matches this regular expression")
+	flag.BoolVar(&cfg.SkipSponsors, "skip-sponsors", false, "Drop caption cues inside SponsorBlock sponsor, self-promotion, interaction, intro and outro segments (needs yt-dlp with SponsorBlock support)")
+	flag.BoolVar(&cfg.StatsOnly, "stats-only", false, "Run as usual but print only aggregate statistics (counts by status, timings, token usage, transcript lengths) instead of the per-video report")
+	flag.BoolVar(&cfg.CleanupOnStart, "cleanup-on-start", false, "Remove subtitle files older than an hour left in the temp directory by earlier runs before starting")
+	flag.BoolVar(&cfg.PreserveSpeakers, "preserve-speakers", false, "Keep caption speaker labels (\">> NAME:\", <v Name>) as one turn per line and ask for a speaker-aware summary")
+	tempPerms := flag.String("temp-perms", "0755", "Octal mode for the temp, -keep-transcripts, -notes and -thumbnails directories; files get the same mode without execute bits, as do all other output files")
+	flag.BoolVar(&cfg.OrderedStream, "ordered-stream", false, "Write -append-jsonl lines in playlist order, holding finished videos until all earlier ones are done")
+	flag.BoolVar(&cfg.AppendJSONLFsync, "append-jsonl-fsync", false, "fsync the -append-jsonl file after every line")
+	compareModels := flag.String("compare-models", "", "Comma-separated Gemini models (e.g. flash,pro) to summarize every video with, side by side")
+	maxCost := flag.Float64("max-cost", 0, "Stop starting new summaries once the estimated Gemini spend reaches this many dollars (0 disables; needs -input-price and -output-price)")
+	inputPrice := flag.Float64("input-price", 0, "Price in dollars per 1K input tokens, for -max-cost")
+	outputPrice := flag.Float64("output-price", 0, "Price in dollars per 1K output tokens, for -max-cost")
+	flag.BoolVar(&cfg.MaxCostStopsFetching, "max-cost-stop-fetching", false, "Once -max-cost is reached, also stop fetching transcripts")
+	flag.StringVar(&cfg.LocalFile, "local-file", "", "Summarize a local video or audio file from its embedded subtitles (uses ffmpeg) instead of YouTube")
+	flag.StringVar(&cfg.LogFile, "log-file", "", "Also write the log to this file (truncated each run unless -log-append)")
+	flag.BoolVar(&cfg.LogToStderr, "log-to-stderr", true, "Keep logging to stderr when -log-file is set")
+	flag.BoolVar(&cfg.LogAppend, "log-append", false, "Append to -log-file instead of truncating it")
+	flag.BoolVar(&cfg.Doctor, "doctor", false, "Check yt-dlp, API keys, network and the temp directory, print a report and exit")
+	proxies := flag.String("proxy", "", "Proxy URL for yt-dlp; a comma-separated list is used round-robin, one proxy per yt-dlp run")
+	addedSince := flag.String("added-since", "", "Only process videos added to the playlist on or after this date (YYYY-MM-DD or RFC 3339)")
+	flag.Parse()
+
+	if cfg.Strict {
+		if err := checkNoDefaultsUsed(cfg); err != nil {
+			return nil, err
+		}
+	}
+	cfg.GeminiModel = resolveGeminiModel(cfg.GeminiModel)
+	if cfg.TranscriptsOnly && cfg.KeepTranscriptsDir == "" {
+		cfg.KeepTranscriptsDir = defaultKeepTranscriptsDir
+	}
+	if cfg.DedupeThreshold < 0 || cfg.DedupeThreshold > 1 {
+		return nil, fmt.Errorf("invalid -dedupe-threshold %v: must be between 0 and 1", cfg.DedupeThreshold)
+	}
+	for _, header := range cfg.YtDlpHeaders {
+		if name, _, ok := strings.Cut(header, ":"); !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid -add-header %q: must be Name:Value", header)
+		}
+	}
+	if cfg.CaptionWait < 0 || cfg.CaptionWaitRetries < 0 {
+		return nil, fmt.Errorf("-caption-wait and -caption-wait-retries must not be negative")
+	}
+	if cfg.ConfirmThreshold < 0 {
+		return nil, fmt.Errorf("invalid -confirm-over %d: must not be negative", cfg.ConfirmThreshold)
+	}
+	if cfg.SummarySentences < 0 {
+		return nil, fmt.Errorf("invalid -sentences %d: must be positive", cfg.SummarySentences)
+	}
+	if cfg.SummarySentences > 0 && flagsSet()["words"] {
+		return nil, fmt.Errorf("-sentences and -words cannot be used together")
+	}
+	if cfg.SummaryWordCount <= 0 {
+		return nil, fmt.Errorf("invalid -words %d: must be positive", cfg.SummaryWordCount)
+	}
+	if cfg.TranscriptJoin != transcriptJoinSpace && cfg.TranscriptJoin != transcriptJoinNewline {
+		return nil, fmt.Errorf("invalid -transcript-join %q: must be %s or %s", cfg.TranscriptJoin, transcriptJoinSpace, transcriptJoinNewline)
+	}
+	if *playlistPrompts != "" {
+		prompts, err := loadPromptTemplates(*playlistPrompts, "playlist")
+		if err != nil {
+			return nil, err
+		}
+		cfg.PlaylistPrompts = prompts
+	}
+	if *languagePrompts != "" {
+		prompts, err := loadPromptTemplates(*languagePrompts, "language")
+		if err != nil {
+			return nil, err
+		}
+		for lang := range prompts {
+			if _, ok := languageStopwords[lang]; !ok {
+				log.Printf("Warning: %s", runWarnings.add(warnConfig, "", "-language-prompts: %q cannot be detected in stored or local transcripts; its template applies to YouTube subtitles only.", lang))
+			}
+		}
+		cfg.LanguagePrompts = prompts
+	}
+	if *modelPrompts != "" {
+		prompts, err := loadPromptTemplates(*modelPrompts, "model")
+		if err != nil {
+			return nil, err
+		}
+		cfg.ModelPrompts = make(map[string]string, len(prompts))
+		for model, template := range prompts {
+			cfg.ModelPrompts[resolveGeminiModel(model)] = template
+		}
+	}
+	if cfg.SummarySentences > 0 && len(cfg.PlaylistPrompts)+len(cfg.LanguagePrompts)+len(cfg.ModelPrompts) > 0 {
+		log.Printf("Warning: %s", runWarnings.add(warnConfig, "", "-sentences does not apply to videos summarized with a -playlist-prompts, -language-prompts or -model-prompts template; the template sets the length."))
+	}
+	if *maxCost < 0 || *inputPrice < 0 || *outputPrice < 0 {
+		return nil, fmt.Errorf("-max-cost, -input-price and -output-price cannot be negative")
+	}
+	if cfg.PositionRange.start < 0 || cfg.PositionRange.end < 0 {
+		return nil, fmt.Errorf("-position-start and -position-end cannot be negative")
+	}
+	if cfg.PositionRange.end > 0 && cfg.PositionRange.start > cfg.PositionRange.end {
+		return nil, fmt.Errorf("-position-start (%d) is after -position-end (%d)", cfg.PositionRange.start, cfg.PositionRange.end)
+	}
+	if cfg.RetitleFileNames && (!cfg.Retitle || cfg.NotesDir == "") {
+		return nil, fmt.Errorf("-retitle-filenames requires -retitle and -notes")
+	}
+	if *maxTotalChars < 0 {
+		return nil, fmt.Errorf("-max-total-chars cannot be negative")
+	}
+	if *maxCost > 0 && *inputPrice == 0 && *outputPrice == 0 {
+		return nil, fmt.Errorf("-max-cost needs -input-price and/or -output-price to estimate spend")
+	}
+	cfg.Usage = &usageTracker{maxCost: *maxCost, inputPricePer1K: *inputPrice, outputPricePer1K: *outputPrice, maxChars: *maxTotalChars}
+	if proxyList := splitCommaList(*proxies); len(proxyList) > 0 {
+		cfg.YtDlpProxies = newProxyRotation(proxyList)
+	}
+	for _, model := range splitCommaList(*compareModels) {
+		cfg.CompareModels = append(cfg.CompareModels, resolveGeminiModel(model))
+	}
+	if len(cfg.CompareModels) == 1 {
+		return nil, fmt.Errorf("-compare-models needs at least two models")
+	}
+	if len(cfg.CompareModels) > 0 && cfg.TranscriptsOnly {
+		return nil, fmt.Errorf("-compare-models cannot be used with -transcripts-only")
+	}
+	cfg.Playlists = splitCommaList(*playlists)
+	cfg.Channels = splitCommaList(*channels)
+	for i, video := range cfg.VideoIDs {
+		id, err := parseVideoID(video)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -video: %w", err)
+		}
+		cfg.VideoIDs[i] = id
+	}
+	playlistIDs := cfg.Playlists
+	if !hasExplicitSources(cfg) && cfg.FromTranscriptsDir == "" && cfg.LocalFile == "" {
+		playlistIDs = []string{cfg.PlaylistID}
+	}
+	for _, playlistID := range playlistIDs {
+		if err := checkPersonalPlaylist(playlistID); err != nil {
+			return nil, err
+		}
+	}
+	if hasExplicitSources(cfg) && cfg.FromTranscriptsDir != "" {
+		return nil, fmt.Errorf("-from-transcripts cannot be combined with -playlists, -channel, -video or -input-file")
+	}
+	if *addedSince != "" {
+		if cfg.FromTranscriptsDir != "" {
+			return nil, fmt.Errorf("-added-since cannot be used with -from-transcripts")
+		}
+		since, err := parseAddedSince(*addedSince)
+		if err != nil {
+			return nil, err
+		}
+		cfg.AddedSince = since
+	}
+	if cfg.CSVPath != "" {
+		columns, err := parseCSVColumns(*csvColumnList)
+		if err != nil {
+			return nil, err
+		}
+		cfg.CSVColumns = columns
+	} else if flagsSet()["csv-columns"] {
+		return nil, fmt.Errorf("-csv-columns requires -csv")
+	}
+	if cfg.ExportFormat != "" {
+		if !isExportFormat(cfg.ExportFormat) {
+			return nil, fmt.Errorf("invalid -export %q: must be one of %s", cfg.ExportFormat, strings.Join(exportFormats, ", "))
+		}
+		if cfg.ExportPath == "" {
+			cfg.ExportPath = "summify-" + cfg.ExportFormat + ".ndjson"
+		}
+	}
+	if len(redactPatterns) > 0 && !*redact {
+		return nil, fmt.Errorf("-redact-pattern requires -redact")
+	}
+	if *redact {
+		r, err := newRedactor(redactPatterns)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Redactor = r
+	}
+	if *contextFile != "" {
+		text, err := loadContextFile(*contextFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.ContextText = text
+	}
+	if cfg.MinConcurrency < 1 || cfg.ConcurrencyLimit < cfg.MinConcurrency {
+		return nil, fmt.Errorf("invalid concurrency bounds: need 1 <= -min-concurrency (%d) <= -max-concurrency (%d)", cfg.MinConcurrency, cfg.ConcurrencyLimit)
+	}
+	if cfg.SeriesContext {
+		cfg.MinConcurrency, cfg.ConcurrencyLimit = 1, 1
+	}
+	prefix, err := parseSummaryTemplate("summary-prefix", *summaryPrefix)
+	if err != nil {
+		return nil, err
+	}
+	suffix, err := parseSummaryTemplate("summary-suffix", *summarySuffix)
+	if err != nil {
+		return nil, err
+	}
+	cfg.SummaryPrefix, cfg.SummarySuffix = prefix, suffix
+	if cfg.OutputRate < 0 || cfg.OutputBurst < 1 {
+		return nil, fmt.Errorf("-output-rate must not be negative and -output-burst must be at least 1")
+	}
+	if cfg.OutputRate > 0 && cfg.PublishURL == "" {
+		return nil, fmt.Errorf("-output-rate needs -publish-url")
+	}
+	if cfg.VerifyMaxChars < 0 {
+		return nil, fmt.Errorf("-verify-max-chars must not be negative")
+	}
+	for _, substring := range splitCommaList(*retryOn) {
+		cfg.RetryOn = append(cfg.RetryOn, strings.ToLower(substring))
+	}
+	if cfg.Sections {
+		cfg.SectionHeaders = splitCommaList(*sectionHeaders)
+		if len(cfg.SectionHeaders) == 0 {
+			return nil, fmt.Errorf("-section-headers needs at least one header")
+		}
+	}
+	if cfg.ChannelLimit < 0 {
+		return nil, fmt.Errorf("-channel-limit must not be negative")
+	}
+	if cfg.ChannelLimit > 0 && len(cfg.Channels) == 0 {
+		return nil, fmt.Errorf("-channel-limit needs -channel")
+	}
+	if cfg.Structured && cfg.Sections {
+		return nil, fmt.Errorf("-structured cannot be used with -sections")
+	}
+	if cfg.Structured && len(cfg.CompareModels) > 0 {
+		return nil, fmt.Errorf("-structured cannot be used with -compare-models")
+	}
+	if cfg.MinQuality < 0 || cfg.MinQuality > 1 {
+		return nil, fmt.Errorf("-min-quality must be between 0 and 1")
+	}
+	if cfg.LowQualityAction != lowQualitySkip && cfg.LowQualityAction != lowQualityFlag {
+		return nil, fmt.Errorf("invalid -low-quality %q: must be %s or %s", cfg.LowQualityAction, lowQualitySkip, lowQualityFlag)
+	}
+	if cfg.PublishURL != "" {
+		if err := validatePublishTopic(cfg.PublishTopic); err != nil {
+			return nil, err
+		}
+		if cfg.PublishBuffer < 1 {
+			return nil, fmt.Errorf("-publish-buffer must be at least 1")
+		}
+	}
+	if err := validateAudience(cfg.Audience); err != nil {
+		return nil, err
+	}
+	if err := validateOrder(cfg.Order); err != nil {
+		return nil, err
+	}
+	if err := validateGroupBy(cfg.GroupBy); err != nil {
+		return nil, err
+	}
+	perms, err := parseTempPerms(*tempPerms)
+	if err != nil {
+		return nil, err
+	}
+	cfg.TempPerms = perms
+	if *titleMatch != "" {
+		pattern, err := regexp.Compile(*titleMatch)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -title-match %q: %w", *titleMatch, err)
+		}
+		cfg.TitleMatch = pattern
+	}
+	if *titleExclude != "" {
+		pattern, err := regexp.Compile(*titleExclude)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -title-exclude %q: %w", *titleExclude, err)
+		}
+		cfg.TitleExclude = pattern
+	}
+	if cfg.StatsOnly {
+		if cfg.Bare {
+			return nil, fmt.Errorf("-stats-only cannot be combined with -bare")
+		}
+	}
+	if cfg.OrderedStream && cfg.AppendJSONL == "" {
+		return nil, fmt.Errorf("-ordered-stream requires -append-jsonl")
+	}
+	if cfg.PreserveSpeakers && (cfg.Cite || cfg.CompactTranscript) {
+		return nil, fmt.Errorf("-preserve-speakers cannot be combined with -cite or -compact")
+	}
+	if cfg.Cite && cfg.CompactTranscript {
+		return nil, fmt.Errorf("-cite cannot be combined with -compact")
+	}
+	if cfg.Color && cfg.NoColor {
+		return nil, fmt.Errorf("-color and -no-color cannot be used together")
+	}
+	if cfg.Estimate && (cfg.TranscriptsOnly || len(cfg.CompareModels) > 0) {
+		return nil, fmt.Errorf("-estimate cannot be combined with -transcripts-only or -compare-models")
+	}
+	if cfg.EmbeddingsPath != "" && cfg.TranscriptsOnly {
+		return nil, fmt.Errorf("-embeddings cannot be used with -transcripts-only")
+	}
+	if cfg.FromTranscriptsDir != "" && cfg.TranscriptsOnly {
+		return nil, fmt.Errorf("-from-transcripts and -transcripts-only cannot be used together")
+	}
+	if cfg.LocalFile != "" {
+		if cfg.FromTranscriptsDir != "" || hasExplicitSources(cfg) {
+			return nil, fmt.Errorf("-local-file cannot be combined with -from-transcripts, -playlist, -channel, -video or -input-file")
+		}
+		if _, err := os.Stat(cfg.LocalFile); err != nil {
+			return nil, fmt.Errorf("-local-file: %w", err)
+		}
+	}
+	if len(cfg.YoutubeAPIKeys) == 0 && cfg.FromTranscriptsDir == "" && cfg.LocalFile == "" && !cfg.Doctor {
+		return nil, fmt.Errorf("%s or %s environment variable must be set", envYoutubeAPIKey, envYoutubeAPIKeys)
 	}
 	return cfg, nil
 }
 
+// splitCommaList splits a comma-separated flag value, dropping empty entries.
+func splitCommaList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseAddedSince accepts either a plain date, taken as midnight UTC, or a full
+// RFC 3339 timestamp.
+func parseAddedSince(value string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid -added-since %q: must be YYYY-MM-DD or RFC 3339", value)
+	}
+	return t, nil
+}
+
+// flagsSet returns the names of the flags given on the command line.
+func flagsSet() map[string]bool {
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	return set
+}
+
+// checkNoDefaultsUsed returns an error naming every setting that was not
+// given explicitly and would silently use a built-in default constant.
+func checkNoDefaultsUsed(cfg *AppConfig) error {
+	setFlags := flagsSet()
+
+	var defaulted []string
+	if os.Getenv(envPlaylistID) == "" && cfg.FromTranscriptsDir == "" && cfg.LocalFile == "" && !hasExplicitSources(cfg) {
+		defaulted = append(defaulted, fmt.Sprintf("playlist ID (set %s; default %s)", envPlaylistID, defaultPlaylistID))
+	}
+	if os.Getenv(envGeminiModel) == "" && !setFlags["model"] && !cfg.TranscriptsOnly {
+		defaulted = append(defaulted, fmt.Sprintf("Gemini model (set %s or -model; default %s)", envGeminiModel, defaultGeminiModel))
+	}
+	if !setFlags["words"] && cfg.SummarySentences == 0 && !cfg.TranscriptsOnly {
+		defaulted = append(defaulted, fmt.Sprintf("summary word count (set -words; default %d)", defaultSummaryWordCount))
+	}
+	if len(defaulted) > 0 {
+		return fmt.Errorf("-strict is set but these values would fall back to built-in defaults: %s", strings.Join(defaulted, "; "))
+	}
+	return nil
+}
+
+// newHTTPClient returns an http.Client whose dial, TLS handshake, response
+// headers and overall request are bounded by timeout, so a hung connection
+// cannot stall a worker regardless of per-call context deadlines. The API key
+// is attached by the transport because option.WithHTTPClient makes the client
+// libraries ignore option.WithAPIKey.
+func newHTTPClient(apiKey string, timeout time.Duration) *http.Client {
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	base.DialContext = (&net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}).DialContext
+	base.TLSHandshakeTimeout = timeout
+	base.ResponseHeaderTimeout = timeout
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: &transport.APIKey{Key: apiKey, Transport: base},
+	}
+}
+
+// apiClientOptions builds the client options shared by the YouTube and Gemini
+// services. The key is always passed as well, since not every client created
+// by the Gemini SDK honours a custom HTTP client.
+func apiClientOptions(apiKey string, httpTimeout time.Duration) []option.ClientOption {
+	opts := []option.ClientOption{option.WithAPIKey(apiKey)}
+	if httpTimeout > 0 {
+		opts = append(opts, option.WithHTTPClient(newHTTPClient(apiKey, httpTimeout)))
+	}
+	return opts
+}
+
 // --- YouTube API Interaction ---
 
-func getYouTubeService(ctx context.Context, apiKey string) (*youtube.Service, error) {
-	service, err := youtube.NewService(ctx, option.WithAPIKey(apiKey))
+func getYouTubeService(ctx context.Context, apiKey string, httpTimeout time.Duration) (*youtube.Service, error) {
+	service, err := youtube.NewService(ctx, apiClientOptions(apiKey, httpTimeout)...)
 	if err != nil {
 		return nil, fmt.Errorf("youtube.NewService: %w", err)
 	}
@@ -109,28 +813,69 @@ func getYouTubeService(ctx context.Context, apiKey string) (*youtube.Service, er
 }
 
 // Modified to return []VideoDetails
-func getPlaylistVideos(service *youtube.Service, playlistID string) ([]VideoDetails, error) {
+// When untilID is set, listing stops at that video (which is excluded) without
+// fetching further pages; playlists are assumed to be ordered newest first.
+// Items outside window are dropped, and listing stops at the first item past
+// its end. A positive limit (-channel-limit) stops listing once that many
+// videos are kept, requesting no more items than needed.
+func getPlaylistVideos(ctx context.Context, yt *rotatingYouTubeService, playlistID, untilID string, window positionRange, limit int, skipped skippedPlaylistItems) ([]VideoDetails, error) {
 	var videos []VideoDetails // Changed type
 	nextPageToken := ""
 	for {
-		call := service.PlaylistItems.List([]string{"snippet", "contentDetails"})
-		call = call.PlaylistId(playlistID)
-		call = call.MaxResults(50)
-		if nextPageToken != "" {
-			call = call.PageToken(nextPageToken)
-		}
-		response, err := call.Do()
+		var response *youtube.PlaylistItemListResponse
+		err := yt.withKeyRotation(ctx, func(service *youtube.Service) error {
+			call := service.PlaylistItems.List([]string{"snippet", "contentDetails", "status"})
+			call = call.PlaylistId(playlistID)
+			call = call.MaxResults(50)
+			if nextPageToken != "" {
+				call = call.PageToken(nextPageToken)
+			}
+			var err error
+			response, err = call.Context(ctx).Do()
+			return err
+		})
 		if err != nil {
 			return nil, fmt.Errorf("PlaylistItems.List call failed for playlist %s: %w", playlistID, err)
 		}
 		for _, item := range response.Items {
-			if item.Snippet != nil && item.ContentDetails != nil && item.ContentDetails.VideoId != "" {
-				videos = append(videos, VideoDetails{ // Changed type
-					ID:    item.ContentDetails.VideoId,
-					Title: item.Snippet.Title,
-				})
+			if untilID != "" && item.ContentDetails != nil && item.ContentDetails.VideoId == untilID {
+				log.Printf("Reached -until-id video %s in playlist %s; stopping after %d newer videos.", untilID, playlistID, len(videos))
+				return videos, nil
+			}
+			if item.Snippet != nil && window.past(item.Snippet.Position) {
+				log.Printf("Reached -position-end %d in playlist %s; stopping after %d videos.", window.end, playlistID, len(videos))
+				return videos, nil
+			}
+			if item.Snippet != nil && !window.includes(item.Snippet.Position) {
+				continue
+			}
+			if reason := playlistItemSkipReason(item); reason != "" {
+				log.Printf("Warning: %s", runWarnings.add(warnPlaylist, "", "Playlist %s: Skipping item ID %s (%s).", playlistID, item.Id, reason))
+				skipped[reason]++
 			} else {
-				log.Printf("Warning: Playlist %s: Skipping item ID %s due to missing details.", playlistID, item.Id)
+				video := VideoDetails{ // Changed type
+					ID:           item.ContentDetails.VideoId,
+					Title:        item.Snippet.Title,
+					Description:  item.Snippet.Description,
+					ThumbnailURL: bestThumbnailURL(item.Snippet.Thumbnails, item.ContentDetails.VideoId),
+					Position:     item.Snippet.Position + 1,
+				}
+				// The playlist item's publishedAt is when it was added to the playlist.
+				if addedAt, err := time.Parse(time.RFC3339, item.Snippet.PublishedAt); err == nil {
+					video.AddedAt = addedAt
+				}
+				if publishedAt, err := time.Parse(time.RFC3339, item.ContentDetails.VideoPublishedAt); err == nil {
+					video.PublishedAt = publishedAt
+				}
+				videos = append(videos, video)
+				if len(videos) == limit {
+					total := int64(0)
+					if response.PageInfo != nil {
+						total = response.PageInfo.TotalResults
+					}
+					log.Printf("Reached -channel-limit %d in uploads playlist %s; fetched %d of its %d videos.", limit, playlistID, len(videos), total)
+					return videos, nil
+				}
 			}
 		}
 		nextPageToken = response.NextPageToken
@@ -138,245 +883,1340 @@ func getPlaylistVideos(service *youtube.Service, playlistID string) ([]VideoDeta
 			break
 		}
 	}
+	if untilID != "" {
+		log.Printf("Warning: %s", runWarnings.add(warnPlaylist, "", "-until-id video %s was not found in playlist %s; all videos were listed.", untilID, playlistID))
+	}
 	log.Printf("Fetched %d videos from playlist %s.", len(videos), playlistID)
 	return videos, nil
 }
 
+// filterAddedSince keeps the videos added to the playlist at or after since.
+// Videos whose addition time is unknown are dropped.
+func filterAddedSince(videos []VideoDetails, since time.Time) []VideoDetails {
+	var kept []VideoDetails
+	for _, video := range videos {
+		if !video.AddedAt.IsZero() && !video.AddedAt.Before(since) {
+			kept = append(kept, video)
+		}
+	}
+	return kept
+}
+
 // --- Transcript Fetching and Parsing --- (getVideoTranscript unchanged from previous step)
-func getVideoTranscript(videoID string, cfg *AppConfig) (string, error) {
+func getVideoTranscript(ctx context.Context, videoID string, cfg *AppConfig) (string, int, error) {
+	return fetchVideoTranscript(ctx, videoID, subtitleFormatVTT, cfg)
+}
+
+// fetchVideoTranscript downloads the video's subtitles in the given format
+// ("vtt" or "srt") and parses them into plain text.
+func fetchVideoTranscript(ctx context.Context, videoID, format string, cfg *AppConfig) (string, int, error) {
+	attempts := 0 // yt-dlp runs made so far, returned with every result
+	trail := explainFrom(ctx)
 	videoURL := "https://www.youtube.com/watch?v=" + videoID
-	if err := os.MkdirAll(cfg.TempTranscriptDir, 0755); err != nil {
-		return "", fmt.Errorf("failed to create temp dir %s for video %s: %w", cfg.TempTranscriptDir, videoID, err)
+	if err := makeDir(cfg.TempTranscriptDir, cfg.TempPerms); err != nil {
+		return "", attempts, fmt.Errorf("failed to create temp dir %s for video %s: %w", cfg.TempTranscriptDir, videoID, err)
+	}
+
+	// Subtitle files left by an earlier run would look like a fresh download.
+	for _, stale := range findSubtitleFiles(cfg.TempTranscriptDir, videoID, format) {
+		os.Remove(stale)
+	}
+	sponsorBlockPath := filepath.Join(cfg.TempTranscriptDir, videoID+sponsorBlockFileSuffix)
+	if cfg.SkipSponsors {
+		os.Remove(sponsorBlockPath)
 	}
 
-	vttFileNamePattern := filepath.Join(cfg.TempTranscriptDir, videoID+".*.vtt")
-	var output []byte
+	// Whether a non-empty subtitle file appeared is the success signal; the
+	// exit code and output only classify runs that produced none.
+	var matches []string
+	var output ytDlpOutput
 	var err error // This err is for yt-dlp command execution
-	var cmd *exec.Cmd
 
 	for attempt := 1; attempt <= cfg.MaxTranscriptRetries; attempt++ {
+		attempts = attempt
 		log.Printf("Video %s: Transcript fetch attempt %d/%d.", videoID, attempt, cfg.MaxTranscriptRetries)
-		cmd = exec.Command("yt-dlp",
-			"--write-auto-sub", "--write-sub",
-			"--sub-format", "vtt",
-			"--sub-langs", "en.*,en",
-			"--skip-download",
-			"-o", filepath.Join(cfg.TempTranscriptDir, "%(id)s.%(ext)s"),
-			videoURL,
-		)
-		log.Printf("Video %s: Running command: %s", videoID, cmd.String())
-		output, err = cmd.CombinedOutput()
+		proxy := cfg.YtDlpProxies.pick()
+		if proxy != "" {
+			log.Printf("Video %s: Attempt %d using proxy %s.", videoID, attempt, redactProxy(proxy))
+		}
+		args := ytDlpArgs(cfg, videoURL, proxy, format)
+		log.Printf("Video %s: Running command: %s %s", videoID, ytDlpCommand, strings.Join(redactYtDlpArgs(args), " "))
+		output, err = runYtDlp(ctx, args)
 
+		matches = findSubtitleFiles(cfg.TempTranscriptDir, videoID, format)
+		trail.note("yt-dlp", "attempt %d/%d (%s): %s, %d subtitle files written", attempt, cfg.MaxTranscriptRetries, format, ytDlpExitStatus(err), len(matches))
+		if len(matches) > 0 {
+			if err != nil {
+				log.Printf("Video %s: yt-dlp exited with %v on attempt %d but wrote subtitles; using them.", videoID, err, attempt)
+				err = nil
+			} else {
+				log.Printf("Video %s: yt-dlp command successful on attempt %d.", videoID, attempt)
+			}
+			break
+		}
 		if err == nil {
-			log.Printf("Video %s: yt-dlp command successful on attempt %d.", videoID, attempt)
-			// Check if successful exit still reported no subtitles in its output
-			if strings.Contains(string(output), "no subtitles") || strings.Contains(string(output), "no suitable subtitles found") {
+			trail.note("subtitles.found", "none")
+			if reportsNoSubtitles(output.combined()) {
 				log.Printf("Video %s: No subtitles found (reported by yt-dlp on successful exit).", videoID)
-				return "", nil // No transcript, not an error for the overall process
+			} else {
+				log.Printf("Video %s: No %s file found after yt-dlp run (output: %s). File may not have been created despite command success.", videoID, strings.ToUpper(format), output.combined())
 			}
-			break // yt-dlp succeeded and didn't say "no subtitles", proceed to parse
+			return "", attempts, nil // No transcript, not an error for the overall process
 		}
-		// yt-dlp command failed (err != nil)
-		errMsgForLog := string(output)
+		// yt-dlp command failed (err != nil) and wrote nothing
+		errMsgForLog := output.combined()
 		log.Printf("Video %s: yt-dlp attempt %d failed: %v\nOutput: %s", videoID, attempt, err, errMsgForLog)
-		if strings.Contains(errMsgForLog, "no subtitles") || strings.Contains(errMsgForLog, "no suitable subtitles found") {
+		if reportsNoSubtitles(errMsgForLog) {
+			trail.note("subtitles.found", "none (reported by yt-dlp)")
 			log.Printf("Video %s: No subtitles found (reported by yt-dlp on failed exit). Will not retry.", videoID)
-			return "", nil // No transcript, not an error for the overall process
+			return "", attempts, nil // No transcript, not an error for the overall process
+		}
+		retry, kind, matched := shouldRetryYtDlp(err, output.stderr, cfg.RetryOn)
+		if !retry {
+			trail.note("yt-dlp", "failure classified as %s; not retried", kind)
+			if cfg.RetryOn != nil {
+				log.Printf("Video %s: yt-dlp failure (%s) matches no -retry-on substring. Will not retry.", videoID, kind)
+			} else {
+				log.Printf("Video %s: yt-dlp failure classified as %s. Will not retry.", videoID, kind)
+			}
+			return "", attempts, fmt.Errorf("yt-dlp command for video %s failed (%s, not retried): %w\nOutput: %s", videoID, kind, err, errMsgForLog)
+		}
+		if matched != "" {
+			log.Printf("Video %s: yt-dlp output matched %q; the failure is retryable.", videoID, matched)
 		}
 		if attempt < cfg.MaxTranscriptRetries {
 			log.Printf("Video %s: Waiting %v before next transcript fetch attempt.", videoID, cfg.TranscriptRetryDelay)
-			time.Sleep(cfg.TranscriptRetryDelay)
+			select {
+			case <-time.After(cfg.TranscriptRetryDelay):
+			case <-ctx.Done():
+				return "", attempts, fmt.Errorf("transcript fetch for video %s cancelled: %w", videoID, ctx.Err())
+			}
 		}
 	}
 
 	if err != nil { // All retries failed for a reason other than "no subtitles"
-		return "", fmt.Errorf("yt-dlp command for video %s failed after %d attempts: %w\nLast Output: %s", videoID, cfg.MaxTranscriptRetries, err, string(output))
+		return "", attempts, fmt.Errorf("yt-dlp command for video %s failed after %d attempts: %w\nLast Output: %s", videoID, cfg.MaxTranscriptRetries, err, output.combined())
 	}
+	log.Printf("Video %s: yt-dlp output (after successful attempt): %s", videoID, output.combined())
 
-	// If we're here, yt-dlp command was successful (err is nil from the loop)
-	// and it didn't report "no subtitles" in its stdout/stderr.
-	log.Printf("Video %s: yt-dlp output (after successful attempt): %s", videoID, string(output))
-
-	// Parsing logic starts here
-	matches, globErr := filepath.Glob(vttFileNamePattern)
-	if globErr != nil {
-		return "", fmt.Errorf("video %s: error searching VTT pattern %s: %w", videoID, vttFileNamePattern, globErr)
+	avoidTranslation := cfg.NoAutoTranslate || cfg.SameLanguage
+	vttFilePath, lang, reason, translatedFrom := pickSubtitleFile(matches, videoID, avoidTranslation)
+	if vttFilePath == "" {
+		langs := make([]string, len(matches))
+		for i, match := range matches {
+			langs[i] = subtitleLanguage(match, videoID)
+		}
+		trail.note("subtitles.found", "%s (original-language only; not used without -no-autotranslate or -same-language)", strings.Join(langs, ", "))
+		log.Printf("Video %s: Only original-language subtitles (%s) were found and neither -no-autotranslate nor -same-language is set; no English transcript.", videoID, strings.Join(langs, ", "))
+		if !cfg.NoCleanup {
+			for _, match := range matches {
+				os.Remove(match)
+			}
+		}
+		return "", attempts, nil // No transcript, not an error for the overall process
+	}
+	videoFactsFrom(ctx).setSubtitleLanguage(lang)
+	if len(matches) > 1 {
+		log.Printf("Video %s: Found %d subtitle variants; using %q (%s).", videoID, len(matches), lang, filepath.Base(vttFilePath))
+	} else {
+		log.Printf("Video %s: Using the %q subtitle track (%s).", videoID, lang, filepath.Base(vttFilePath))
 	}
-	if len(matches) == 0 {
-		vttFileNamePattern = filepath.Join(cfg.TempTranscriptDir, videoID+".vtt") // Fallback
-		matches, _ = filepath.Glob(vttFileNamePattern)
-		if len(matches) == 0 {
-			log.Printf("Video %s: No VTT file found after yt-dlp run (output: %s). File may not have been created despite command success.", videoID, string(output))
-			return "", nil // File not found
+	if trail != nil {
+		langs := make([]string, len(matches))
+		for i, match := range matches {
+			langs[i] = subtitleLanguage(match, videoID)
+		}
+		trail.note("subtitles.found", "%s", strings.Join(langs, ", "))
+		trail.note("subtitles.chosen", "%s (%s)", lang, reason)
+		if translatedFrom != "" {
+			trail.note("subtitles.translated", "English tracks look auto-translated from %q", translatedFrom)
+		}
+		if cfg.NoCleanup {
+			trail.note("subtitles.kept", "%s (-no-cleanup)", strings.Join(matches, ", "))
 		}
 	}
-	vttFilePath := matches[0]
-	defer os.Remove(vttFilePath)
-
-	subs, openErr := astisub.OpenFile(vttFilePath)
-	if openErr != nil {
-		return "", fmt.Errorf("video %s: failed to open/parse VTT file %s: %w", videoID, vttFilePath, openErr)
+	if translatedFrom != "" && !avoidTranslation {
+		log.Printf("Video %s: Warning: %s", videoID, runWarnings.add(warnTranscript, videoID, "English captions appear to be auto-translated from %q; summary quality may suffer (see -no-autotranslate).", translatedFrom))
 	}
-	var transcriptBuilder strings.Builder
-	for _, item := range subs.Items {
-		for _, line := range item.Lines {
-			for _, lineItem := range line.Items {
-				transcriptBuilder.WriteString(lineItem.Text)
-				transcriptBuilder.WriteString(" ")
-			}
+	if cfg.NoCleanup {
+		log.Printf("Video %s: Keeping subtitle files %s (-no-cleanup).", videoID, strings.Join(matches, ", "))
+	} else {
+		for _, match := range matches {
+			defer os.Remove(match)
 		}
-		transcriptBuilder.WriteString(" ")
 	}
-	fullTranscript := strings.TrimSpace(transcriptBuilder.String())
+
+	var fullTranscript, transcriptFormat, readMode string
+	var cues int
+	allocatedBefore := totalAllocated()
+	if streamingSubtitles(cfg) {
+		var streamErr error
+		fullTranscript, cues, streamErr = streamPlainTranscript(vttFilePath, cfg.TranscriptJoin)
+		if streamErr != nil {
+			return "", attempts, fmt.Errorf("video %s: failed to read subtitle file %s: %w", videoID, vttFilePath, streamErr)
+		}
+		transcriptFormat, readMode = "plain text (streamed, -stream-subtitles)", "Streamed"
+	} else {
+		var parseErr error
+		fullTranscript, transcriptFormat, cues, parseErr = parseTranscriptFile(ctx, videoID, vttFilePath, sponsorBlockPath, cfg)
+		if parseErr != nil {
+			return "", attempts, parseErr
+		}
+		readMode = "Parsed"
+	}
+	if info, statErr := os.Stat(vttFilePath); statErr == nil {
+		log.Printf("Video %s: %s %d cues from a %d KiB subtitle file with %d KiB allocated.", videoID, readMode, cues, info.Size()/1024, (totalAllocated()-allocatedBefore)/1024)
+	}
+	trail.note("transcript", "%s from %d cues, %d characters", transcriptFormat, cues, utf8.RuneCountInString(fullTranscript))
 	if fullTranscript == "" {
 		log.Printf("Video %s: Parsed transcript from %s is empty.", videoID, vttFilePath)
-		return "", nil
+		if format == subtitleFormatVTT && cfg.RetryEmptyTranscript {
+			log.Printf("Video %s: Retrying the fetch with SRT subtitles (-retry-empty-transcript).", videoID)
+			transcript, more, err := fetchVideoTranscript(ctx, videoID, subtitleFormatSRT, cfg)
+			return transcript, attempts + more, err
+		}
+		return "", attempts, nil
 	}
 	log.Printf("Video %s: Successfully parsed transcript from %s.", videoID, vttFilePath)
-	return fullTranscript, nil
+	return fullTranscript, attempts, nil
 }
 
-// --- LLM Interaction --- (summarizeTranscriptWithGemini unchanged from previous step)
-func summarizeTranscriptWithGemini(ctx context.Context, geminiModel *genai.GenerativeModel, transcript string, cfg *AppConfig) (string, error) {
-	if transcript == "" {
-		return "Transcript was empty, no summary generated.", nil
-	}
-
-	prompt := fmt.Sprintf(summaryPromptFormat, cfg.SummaryWordCount, transcript)
-	llmCtx, cancel := context.WithTimeout(ctx, cfg.LLMTimeout)
-	defer cancel()
-
-	resp, err := geminiModel.GenerateContent(llmCtx, genai.Text(prompt))
+// parseTranscriptFile parses a subtitle file in full with astisub and builds
+// the transcript in the configured format: plain text, or -cite, -preserve-
+// speakers or -compact output, after dropping -skip-sponsors segments. It
+// returns the transcript, a description of its format for the trail and the
+// number of cues.
+func parseTranscriptFile(ctx context.Context, videoID, path, sponsorBlockPath string, cfg *AppConfig) (transcript, format string, cues int, err error) {
+	trail := explainFrom(ctx)
+	subs, err := openSubtitles(path, cfg.StrictParse)
 	if err != nil {
-		return "", fmt.Errorf("gemini GenerateContent failed: %w", err)
+		return "", "", 0, fmt.Errorf("video %s: failed to open/parse subtitle file %s: %w", videoID, path, err)
+	}
+	if cfg.SkipSponsors {
+		segments, segmentsErr := readSponsorSegments(sponsorBlockPath)
+		if segmentsErr != nil {
+			log.Printf("Video %s: Warning: %s", videoID, runWarnings.add(warnTranscript, videoID, "%v; keeping all cues.", segmentsErr))
+			trail.note("cleaning", "SponsorBlock segments unavailable; all cues kept")
+		} else {
+			removed := removeSponsoredCues(subs, segments)
+			trail.note("cleaning", "removed %d cues inside %d SponsorBlock segments", removed, len(segments))
+			log.Printf("Video %s: Removed %d caption cues inside %d SponsorBlock segments.", videoID, removed, len(segments))
+		}
+		if !cfg.NoCleanup {
+			os.Remove(sponsorBlockPath)
+		}
 	}
-	if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
-		return "", fmt.Errorf("gemini returned no content candidates")
+	transcript = buildPlainTranscript(subs, cfg.TranscriptJoin)
+	format = "plain text"
+	if cfg.Cite || cfg.PreserveSpeakers || cfg.CompactTranscript {
+		videoFactsFrom(ctx).setPlainTranscript(transcript)
 	}
-	summaryPart, ok := resp.Candidates[0].Content.Parts[0].(genai.Text)
-	if !ok {
-		return "", fmt.Errorf("gemini returned unexpected content part type: %T", resp.Candidates[0].Content.Parts[0])
+	if cfg.Cite {
+		transcript = buildTimestampedTranscript(subs)
+		format = "timestamped lines (-cite)"
+	}
+	if cfg.PreserveSpeakers {
+		transcript = buildSpeakerTranscript(subs)
+		format = "speaker turns (-preserve-speakers)"
+	}
+	if cfg.CompactTranscript && transcript != "" {
+		compactTranscript := buildCompactTranscript(subs)
+		reduction := 100 * float64(len(transcript)-len(compactTranscript)) / float64(len(transcript))
+		log.Printf("Video %s: Compact transcript is %d chars (down from %d, %.1f%% reduction).", videoID, len(compactTranscript), len(transcript), reduction)
+		transcript = compactTranscript
+		format = fmt.Sprintf("compact (-compact, %.1f%% smaller)", reduction)
 	}
-	return strings.TrimSpace(string(summaryPart)), nil
+	return transcript, format, len(subs.Items), nil
 }
 
-// --- Main Application ---
-func main() {
-	runStart := time.Now()
-	log.SetFlags(log.Ldate | log.Ltime | log.Lmicroseconds)
-	log.Printf("Application starting...")
-
-	loadEnvironmentFile()
-	cfg, err := initializeAppConfig()
-	if err != nil {
-		log.Fatalf("CRITICAL: Failed to initialize application configuration: %v", err)
+// getTranscriptWaitingForCaptions calls getVideoTranscript and, when -caption-wait
+// is set and a recent upload has no captions yet, waits and tries again a
+// bounded number of times. Attempts from every call are added up.
+func getTranscriptWaitingForCaptions(ctx context.Context, video VideoDetails, cfg *AppConfig) (string, int, error) {
+	transcript, attempts, err := getVideoTranscript(ctx, video.ID, cfg)
+	if cfg.CaptionWait <= 0 || video.PublishedAt.IsZero() || time.Since(video.PublishedAt) > captionWaitMaxAge {
+		return transcript, attempts, err
+	}
+	for wait := 1; wait <= cfg.CaptionWaitRetries && err == nil && transcript == ""; wait++ {
+		log.Printf("Video %s: No captions yet for upload published %v ago; waiting %v (caption wait %d/%d).",
+			video.ID, time.Since(video.PublishedAt).Round(time.Minute), cfg.CaptionWait, wait, cfg.CaptionWaitRetries)
+		select {
+		case <-time.After(cfg.CaptionWait):
+		case <-ctx.Done():
+			return "", attempts, fmt.Errorf("caption wait for video %s cancelled: %w", video.ID, ctx.Err())
+		}
+		var more int
+		transcript, more, err = getVideoTranscript(ctx, video.ID, cfg)
+		attempts += more
 	}
+	return transcript, attempts, err
+}
 
-	log.Printf("--- Application Configuration ---")
-	log.Printf("Playlist ID: %s", cfg.PlaylistID)
-	log.Printf("Gemini Model: %s", cfg.GeminiModel)
-	log.Printf("Summary Word Count: %d", cfg.SummaryWordCount)
-	log.Printf("Concurrency Limit: %d", cfg.ConcurrencyLimit)
-	youtubeKeyStatus := "NOT LOADED"
-	if cfg.YoutubeAPIKey != "" {
-		youtubeKeyStatus = "LOADED"
+// saveTranscript writes transcript to <dir>/<videoID>.txt and returns the path.
+func saveTranscript(dir, videoID, transcript string, perm os.FileMode) (string, error) {
+	if err := makeDir(dir, perm); err != nil {
+		return "", fmt.Errorf("failed to create transcript dir %s: %w", dir, err)
 	}
-	log.Printf("YouTube API Key: [%s]", youtubeKeyStatus)
-	geminiKeyStatus := "NOT LOADED - Summarization will be skipped"
-	if cfg.GeminiAPIKey != "" {
-		geminiKeyStatus = "LOADED"
+	path := filepath.Join(dir, videoID+storedTranscriptExt)
+	if err := os.WriteFile(path, []byte(transcript), filePerm(perm)); err != nil {
+		return "", fmt.Errorf("failed to save transcript for video %s: %w", videoID, err)
 	}
-	log.Printf("Gemini API Key: [%s]", geminiKeyStatus)
-	log.Println("-------------------------------")
+	return path, nil
+}
 
-	ctx := context.Background()
-	var geminiClient *genai.GenerativeModel
-	if cfg.GeminiAPIKey != "" {
-		client, errClient := genai.NewClient(ctx, option.WithAPIKey(cfg.GeminiAPIKey)) // Renamed err to errClient
-		if errClient != nil {
-			log.Printf("Warning: Failed to create Gemini client (key was present): %v. Summarization will be skipped.", errClient)
-		} else {
-			geminiClient = client.GenerativeModel(cfg.GeminiModel)
-			log.Printf("Successfully initialized Gemini client with model %s.", cfg.GeminiModel)
+// listStoredTranscripts returns a VideoDetails for every <videoID>.txt file in
+// dir. Titles come from an optional titles.tsv index ("<videoID>\t<title>"
+// per line) and default to the video ID.
+func listStoredTranscripts(dir string) ([]VideoDetails, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transcript dir %s: %w", dir, err)
+	}
+	titles := make(map[string]string)
+	if index, err := os.ReadFile(filepath.Join(dir, storedTranscriptIndexFile)); err == nil {
+		for _, line := range strings.Split(string(index), "\n") {
+			if id, title, ok := strings.Cut(strings.TrimRight(line, "\r"), "\t"); ok {
+				titles[strings.TrimSpace(id)] = strings.TrimSpace(title)
+			}
 		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		log.Printf("Warning: %s", runWarnings.add(warnTranscript, "", "Could not read transcript index %s: %v", storedTranscriptIndexFile, err))
 	}
 
-	youtubeService, err := getYouTubeService(ctx, cfg.YoutubeAPIKey)
-	if err != nil {
-		log.Fatalf("CRITICAL: Failed to create YouTube service: %v", err)
+	var videos []VideoDetails
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != storedTranscriptExt {
+			continue
+		}
+		id := strings.TrimSuffix(entry.Name(), storedTranscriptExt)
+		title := titles[id]
+		if title == "" {
+			title = id
+		}
+		videos = append(videos, VideoDetails{ID: id, Title: title})
 	}
-	log.Printf("Successfully initialized YouTube service.")
+	log.Printf("Found %d stored transcripts in %s.", len(videos), dir)
+	return videos, nil
+}
 
-	videos, err := getPlaylistVideos(youtubeService, cfg.PlaylistID) // videos is now []VideoDetails
+// readStoredTranscript loads the transcript saved for videoID in dir.
+func readStoredTranscript(dir, videoID string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, videoID+storedTranscriptExt))
 	if err != nil {
-		log.Fatalf("CRITICAL: Failed to fetch video details from playlist %s: %v", cfg.PlaylistID, err)
-	}
-	if len(videos) == 0 {
-		log.Printf("No videos found in playlist %s. Exiting.", cfg.PlaylistID)
-		return
+		return "", fmt.Errorf("failed to read stored transcript for video %s: %w", videoID, err)
 	}
+	return strings.TrimSpace(string(data)), nil
+}
 
-	log.Printf("--- Processing %d Videos Concurrently (Limit: %d) ---", len(videos), cfg.ConcurrencyLimit)
+// buildPlainTranscript joins the text of every cue with spaces, or, in
+// newline mode, puts each caption line on its own line so the model can see
+// where utterances begin and end.
+func buildPlainTranscript(subs *astisub.Subtitles, join string) string {
+	if join == transcriptJoinNewline {
+		var lines []string
+		for _, item := range subs.Items {
+			for _, line := range item.Lines {
+				var words []string
+				for _, lineItem := range line.Items {
+					words = append(words, strings.Fields(lineItem.Text)...)
+				}
+				if len(words) > 0 {
+					lines = append(lines, strings.Join(words, " "))
+				}
+			}
+		}
+		return strings.Join(lines, "\n")
+	}
 
-	var wg sync.WaitGroup
-	// resultsChannel now carries ProcessingResult
-	resultsChannel := make(chan ProcessingResult, len(videos))
-	semaphore := make(chan struct{}, cfg.ConcurrencyLimit)
+	var transcriptBuilder strings.Builder
+	for _, item := range subs.Items {
+		for _, line := range item.Lines {
+			for _, lineItem := range line.Items {
+				transcriptBuilder.WriteString(lineItem.Text)
+				transcriptBuilder.WriteString(" ")
+			}
+		}
+		transcriptBuilder.WriteString(" ")
+	}
+	return strings.TrimSpace(transcriptBuilder.String())
+}
 
-	for _, video := range videos { // video is VideoDetails
-		wg.Add(1)
-		semaphore <- struct{}{}
+// buildCompactTranscript produces a denser transcript in a single pass over the
+// cues: whitespace is collapsed, words repeated from the end of the previous
+// cue (rolling auto-captions) are dropped, and consecutive cues are merged into
+// paragraphs that break only on pauses longer than compactParagraphGap.
+func buildCompactTranscript(subs *astisub.Subtitles) string {
+	var words []string
+	var paragraphStarts []int
+	var lastEnd time.Duration
+	for _, item := range subs.Items {
+		var cueWords []string
+		for _, line := range item.Lines {
+			for _, lineItem := range line.Items {
+				cueWords = append(cueWords, strings.Fields(lineItem.Text)...)
+			}
+		}
+		if len(cueWords) == 0 {
+			continue
+		}
+		if len(words) > 0 && item.StartAt-lastEnd > compactParagraphGap {
+			paragraphStarts = append(paragraphStarts, len(words))
+		}
+		lastEnd = item.EndAt
+		words = append(words, cueWords[overlapLength(words, cueWords):]...)
+	}
 
-		go func(v VideoDetails, currentCfg *AppConfig, currentGeminiClient *genai.GenerativeModel) {
-			defer wg.Done()
-			defer func() { <-semaphore }()
-
-			log.Printf("Video %s (%s): Worker started.", v.ID, v.Title)
-			// Initialize ProcessingResult with VideoDetails
-			currentProcessingResult := ProcessingResult{VideoDetails: v}
-
-			transcript, transcriptErr := getVideoTranscript(v.ID, currentCfg) // transcriptErr
-			if transcriptErr != nil {
-				log.Printf("Video %s (%s): Could not get transcript: %v", v.ID, v.Title, transcriptErr)
-				currentProcessingResult.Err = transcriptErr // Store the error object
-				resultsChannel <- currentProcessingResult
-				return
+	var paragraphs []string
+	start := 0
+	for _, end := range append(paragraphStarts, len(words)) {
+		if end > start {
+			paragraphs = append(paragraphs, strings.Join(words[start:end], " "))
+		}
+		start = end
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// overlapLength returns the length of the longest suffix of previous that is
+// also a prefix of next.
+func overlapLength(previous, next []string) int {
+	for k := min(len(previous), len(next)); k > 0; k-- {
+		match := true
+		for i := 0; i < k; i++ {
+			if previous[len(previous)-k+i] != next[i] {
+				match = false
+				break
 			}
+		}
+		if match {
+			return k
+		}
+	}
+	return 0
+}
+
+// --- LLM Interaction --- (summarizeTranscriptWithGemini unchanged from previous step)
+
+// emptyTranscriptSummary stands in for the summary of an empty transcript.
+const emptyTranscriptSummary = "Transcript was empty, no summary generated."
+
+// summarizeTranscriptWithGemini builds the summary prompt and sends it. A
+// non-empty template replaces the global prompt; see -playlist-prompts.
+// Otherwise the model's -model-prompts template is used, if any.
+func summarizeTranscriptWithGemini(ctx context.Context, gemini *rotatingGeminiModel, transcript string, chapters []Chapter, template string, cfg *AppConfig) (string, int, error) {
+	if transcript == "" {
+		return emptyTranscriptSummary, 0, nil
+	}
+	template = modelPromptTemplate(gemini.modelName, template, cfg)
+	return generateWithGemini(ctx, gemini, buildSummaryPrompt(transcript, chapters, template, cfg), cfg)
+}
+
+// buildSummaryPrompt assembles the summary prompt for a transcript from the
+// configured length, an optional template, chapters and the language,
+// -audience, -sections and -structured instructions, preceded by the
+// -series-context summary and -context-file notes.
+func buildSummaryPrompt(transcript string, chapters []Chapter, template string, cfg *AppConfig) string {
+	prompt := fmt.Sprintf(summaryPromptFormat, cfg.SummaryWordCount, transcript)
+	if cfg.SummarySentences > 0 {
+		prompt = fmt.Sprintf(sentencePromptFormat, cfg.SummarySentences, transcript)
+	}
+	if template != "" {
+		prompt = expandPromptTemplate(template, cfg.SummaryWordCount, transcript)
+	}
+	if len(chapters) > 0 {
+		prompt += fmt.Sprintf(chapterPromptFormat, formatChapters(chapters))
+	}
+	if cfg.SameLanguage {
+		prompt += sameLanguagePrompt
+	}
+	if cfg.Cite {
+		prompt += citePrompt
+	}
+	if cfg.PreserveSpeakers {
+		prompt += speakerPrompt
+	}
+	if cfg.Audience != "" {
+		prompt += audiencePrompts[cfg.Audience]
+	}
+	if cfg.Sections {
+		prompt += sectionsPrompt(cfg.SectionHeaders)
+	}
+	if cfg.Structured {
+		prompt += structuredPrompt
+	}
+	if cfg.PreviousSummary != "" {
+		prompt = fmt.Sprintf(seriesPromptFormat, cfg.PreviousSummary) + prompt
+	}
+	if cfg.ContextText != "" {
+		prompt = fmt.Sprintf(contextPromptFormat, cfg.ContextText) + prompt
+	}
+	return prompt
+}
+
+// loadContextFile reads the -context-file notes, truncating them to
+// maxContextFileRunes so they cannot crowd out the transcript.
+func loadContextFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read context file %s: %w", path, err)
+	}
+	text := strings.TrimSpace(string(data))
+	if text == "" {
+		return "", fmt.Errorf("context file %s is empty", path)
+	}
+	if runes := []rune(text); len(runes) > maxContextFileRunes {
+		log.Printf("Warning: %s", runWarnings.add(warnConfig, "", "Context file %s has %d characters; only the first %d are used.", path, len(runes), maxContextFileRunes))
+		text = string(runes[:maxContextFileRunes])
+	}
+	return text, nil
+}
+
+// estimateTokens approximates the token count of text at about four
+// characters per token, close enough to plan budgets without calling Gemini.
+func estimateTokens(text string) int {
+	return (len(text) + charsPerTokenEstimate - 1) / charsPerTokenEstimate
+}
+
+// finishReasonError reports a Gemini response that did not stop naturally.
+type finishReasonError struct {
+	Reason genai.FinishReason
+}
+
+func (e *finishReasonError) Error() string {
+	switch e.Reason {
+	case genai.FinishReasonMaxTokens:
+		return "gemini stopped at the output token limit (MAX_TOKENS); the text is truncated"
+	case genai.FinishReasonSafety:
+		return "gemini blocked the response for safety reasons (SAFETY)"
+	case genai.FinishReasonRecitation:
+		return "gemini blocked the response as recitation of existing content (RECITATION)"
+	default:
+		return fmt.Sprintf("gemini stopped unexpectedly (%s)", e.Reason)
+	}
+}
+
+// isTruncatedResponse reports whether err only means the text returned with it
+// was cut off at the output token limit.
+func isTruncatedResponse(err error) bool {
+	var reasonErr *finishReasonError
+	return errors.As(err, &reasonErr) && reasonErr.Reason == genai.FinishReasonMaxTokens
+}
+
+// combineDescription prepends a video's description to its transcript with
+// clear delimiters, capping the description so it cannot crowd out the
+// transcript.
+func combineDescription(description, transcript string) string {
+	description = strings.TrimSpace(description)
+	if runes := []rune(description); len(runes) > maxCombinedDescriptionRunes {
+		description = string(runes[:maxCombinedDescriptionRunes]) + "..."
+	}
+	return fmt.Sprintf(combinedDescriptionFormat, description, transcript)
+}
+
+// candidateText concatenates the text parts of a candidate, skipping parts
+// of other types. ok is false when the candidate holds no text part.
+func candidateText(candidate *genai.Candidate) (text string, ok bool) {
+	if candidate.Content == nil {
+		return "", false
+	}
+	var builder strings.Builder
+	for _, part := range candidate.Content.Parts {
+		if t, isText := part.(genai.Text); isText {
+			builder.WriteString(string(t))
+			ok = true
+		}
+	}
+	return strings.TrimSpace(builder.String()), ok
+}
+
+// generateWithGemini sends prompt to the active Gemini model, rotating to the
+// next API key on quota errors, and returns the trimmed text of the response
+// along with the number of requests it took. When the response hit the token
+// limit the partial text is returned together with a *finishReasonError.
+func generateWithGemini(ctx context.Context, gemini *rotatingGeminiModel, prompt string, cfg *AppConfig) (string, int, error) {
+	return generateWithGeminiSchema(ctx, gemini, prompt, nil, cfg)
+}
+
+// generateWithGeminiSchema is generateWithGemini with a JSON response
+// schema, which is set on a copy of the shared model for this call only.
+// A nil schema asks for plain text.
+func generateWithGeminiSchema(ctx context.Context, gemini *rotatingGeminiModel, prompt string, schema *genai.Schema, cfg *AppConfig) (string, int, error) {
+	generate := func(model *genai.GenerativeModel) (*genai.GenerateContentResponse, error) {
+		llmCtx, cancel := context.WithTimeout(ctx, cfg.LLMTimeout)
+		defer cancel()
+		if schema != nil {
+			schemaModel := *model
+			schemaModel.ResponseMIMEType = "application/json"
+			schemaModel.ResponseSchema = schema
+			model = &schemaModel
+		}
+		return model.GenerateContent(llmCtx, genai.Text(prompt))
+	}
+
+	trail := explainFrom(ctx)
+	trail.note("gemini.prompt", "%s: %d characters (about %d tokens)", gemini.modelName, utf8.RuneCountInString(prompt), estimateTokens(prompt))
+	if isSummaryCall(ctx) {
+		videoFactsFrom(ctx).recordPrompt(gemini.modelName, prompt)
+	}
+	ctx, generateSpan := startChild(ctx, "gen_ai.generate_content")
+	defer generateSpan.end()
+	generateSpan.setAttribute("gen_ai.system", "gemini")
+	generateSpan.setAttribute("gen_ai.request.model", gemini.modelName)
+	var resp *genai.GenerateContentResponse
+	attempts, err := gemini.withKeyRotation(ctx, func(model *genai.GenerativeModel, _ *genai.Client) error {
+		var err error
+		resp, err = generate(model)
+		return err
+	})
+	trail.note("gemini.attempts", "%d", attempts)
+	generateSpan.setAttribute("summify.attempts", attempts)
+	generateSpan.setError(err)
+	var blocked *genai.BlockedError
+	if errors.As(err, &blocked) && blocked.Candidate != nil {
+		trail.note("gemini.finish", "%s (response blocked)", blocked.Candidate.FinishReason)
+		log.Printf("Gemini finish reason: %s", blocked.Candidate.FinishReason)
+		return "", attempts, &finishReasonError{Reason: blocked.Candidate.FinishReason}
+	}
+	if err != nil {
+		return "", attempts, fmt.Errorf("gemini GenerateContent failed: %w", err)
+	}
+	cfg.Usage.record(resp.UsageMetadata)
+	if usage := resp.UsageMetadata; usage != nil {
+		generateSpan.setAttribute("gen_ai.usage.input_tokens", int(usage.PromptTokenCount))
+		generateSpan.setAttribute("gen_ai.usage.output_tokens", int(usage.CandidatesTokenCount))
+	}
+	if len(resp.Candidates) == 0 {
+		return "", attempts, fmt.Errorf("gemini returned no content candidates")
+	}
+	candidate := resp.Candidates[0]
+	if usage := resp.UsageMetadata; usage != nil {
+		trail.note("gemini.finish", "%s (%d input, %d output tokens)", candidate.FinishReason, usage.PromptTokenCount, usage.CandidatesTokenCount)
+	} else {
+		trail.note("gemini.finish", "%s", candidate.FinishReason)
+	}
+	// The finish reason is checked before the content: a response cut off at
+	// the token limit can arrive with no parts at all, and must still be
+	// reported as truncated rather than as empty.
+	text, ok := candidateText(candidate)
+	generateSpan.setAttribute("gen_ai.response.finish_reasons", candidate.FinishReason.String())
+	switch reason := candidate.FinishReason; reason {
+	case genai.FinishReasonStop, genai.FinishReasonUnspecified:
+	case genai.FinishReasonMaxTokens:
+		log.Printf("Gemini finish reason: %s", reason)
+		return text, attempts, &finishReasonError{Reason: reason}
+	default:
+		log.Printf("Gemini finish reason: %s", reason)
+		generateSpan.setError(&finishReasonError{Reason: reason})
+		return "", attempts, &finishReasonError{Reason: reason}
+	}
+	if candidate.Content == nil || len(candidate.Content.Parts) == 0 {
+		return "", attempts, fmt.Errorf("gemini returned no content candidates")
+	}
+	if !ok {
+		return "", attempts, fmt.Errorf("gemini returned no text parts (first part type: %T)", candidate.Content.Parts[0])
+	}
+	return text, attempts, nil
+}
 
-			if transcript == "" {
-				log.Printf("Video %s (%s): No transcript found or extracted.", v.ID, v.Title)
-				currentProcessingResult.Err = fmt.Errorf("no transcript available") // Use error type
+// validateGeminiModel looks the configured model up once at startup so that a
+// typo in GEMINI_MODEL fails fast instead of erroring on every video. If the
+// model info endpoint cannot be reached the check is skipped with a warning.
+func validateGeminiModel(ctx context.Context, client *genai.Client, modelName string) error {
+	infoCtx, cancel := context.WithTimeout(ctx, defaultModelInfoTimeout)
+	defer cancel()
+
+	info, err := client.GenerativeModel(modelName).Info(infoCtx)
+	if err == nil {
+		log.Printf("Validated Gemini model %s (%s, version %s).", modelName, info.DisplayName, info.Version)
+		return nil
+	}
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusNotFound {
+		log.Printf("Warning: %s", runWarnings.add(warnConfig, "", "Could not validate Gemini model %s (model info unavailable): %v. Continuing without validation.", modelName, err))
+		return nil
+	}
+
+	suggestions := suggestGeminiModels(ctx, client, modelName)
+	if len(suggestions) == 0 {
+		return fmt.Errorf("gemini model %q does not exist", modelName)
+	}
+	return fmt.Errorf("gemini model %q does not exist; close matches: %s", modelName, strings.Join(suggestions, ", "))
+}
+
+// suggestGeminiModels returns the available model names closest to modelName.
+// Listing failures are logged and yield no suggestions.
+func suggestGeminiModels(ctx context.Context, client *genai.Client, modelName string) []string {
+	listCtx, cancel := context.WithTimeout(ctx, defaultModelInfoTimeout)
+	defer cancel()
+
+	type candidate struct {
+		name     string
+		distance int
+	}
+	var candidates []candidate
+	target := strings.ToLower(strings.TrimPrefix(modelName, "models/"))
+	maxDistance := len(target)/3 + 1
+
+	it := client.ListModels(listCtx)
+	for {
+		info, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			log.Printf("Warning: %s", runWarnings.add(warnConfig, "", "Could not list Gemini models for suggestions: %v", err))
+			break
+		}
+		name := strings.TrimPrefix(info.Name, "models/")
+		lowerName := strings.ToLower(name)
+		distance := levenshteinDistance(target, lowerName)
+		if distance <= maxDistance || strings.Contains(lowerName, target) || strings.Contains(target, lowerName) {
+			candidates = append(candidates, candidate{name: name, distance: distance})
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].name < candidates[j].name
+	})
+	var names []string
+	for i := 0; i < len(candidates) && i < maxModelSuggestions; i++ {
+		names = append(names, candidates[i].name)
+	}
+	return names
+}
+
+// levenshteinDistance returns the edit distance between a and b.
+func levenshteinDistance(a, b string) int {
+	ar, br := []rune(a), []rune(b)
+	prev := make([]int, len(br)+1)
+	curr := make([]int, len(br)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ar); i++ {
+		curr[0] = i
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(br)]
+}
+
+// --- Output ---
+
+// previewText returns the first n words of text followed by an ellipsis, or
+// text unchanged when n is not positive or text is already short enough.
+func previewText(text string, n int) string {
+	words := strings.Fields(text)
+	if n <= 0 || len(words) <= n {
+		return text
+	}
+	return strings.Join(words[:n], " ") + "..."
+}
+
+// countSentences counts runs of sentence-terminating punctuation, plus a final
+// sentence that lacks one. It is a heuristic: abbreviations such as "e.g."
+// are counted as sentence ends.
+func countSentences(text string) int {
+	count := 0
+	inTerminator := false
+	trailing := false
+	for _, r := range text {
+		switch r {
+		case '.', '!', '?', '。', '！', '？':
+			if !inTerminator && trailing {
+				count++
+			}
+			inTerminator, trailing = true, false
+		default:
+			inTerminator = false
+			if !unicode.IsSpace(r) && !unicode.IsPunct(r) {
+				trailing = true
+			}
+		}
+	}
+	if trailing {
+		count++
+	}
+	return count
+}
+
+// describeSources summarizes where this run's videos came from, for headers,
+// naming playlists by title when it has been looked up.
+func describeSources(cfg *AppConfig, titles *playlistTitleCache) string {
+	if cfg.FromTranscriptsDir != "" {
+		return "stored transcripts in " + cfg.FromTranscriptsDir
+	}
+	if !hasExplicitSources(cfg) {
+		return "playlist " + titles.label(cfg.PlaylistID)
+	}
+	var parts []string
+	for _, playlistID := range cfg.Playlists {
+		parts = append(parts, "playlist "+titles.label(playlistID))
+	}
+	for _, channel := range cfg.Channels {
+		parts = append(parts, "channel "+channel)
+	}
+	if len(cfg.VideoIDs) > 0 {
+		parts = append(parts, fmt.Sprintf("%d individual video(s)", len(cfg.VideoIDs)))
+	}
+	if cfg.InputFile != "" {
+		parts = append(parts, "input file "+cfg.InputFile)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// writeTranscriptIndex (re)writes <dir>/index.md listing every video of the
+// run with a link to its saved transcript and thumbnail and a one-line status,
+// so the -keep-transcripts folder can be browsed in Obsidian or a static site.
+// Unless placeholder is empty, it stands in for the summary of videos without
+// one, followed by their status in parentheses.
+func writeTranscriptIndex(dir, heading string, groups []videoGroup, allResults map[string]ProcessingResult, placeholder string, generated time.Time, perm os.FileMode) (string, error) {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "# Summify: %s\n\nGenerated %s.\n\n", heading, generated.Format(time.RFC1123))
+	for i, group := range groups {
+		if group.Name != "" {
+			if i > 0 {
+				builder.WriteString("\n")
+			}
+			fmt.Fprintf(&builder, "## %s (%d)\n\n", group.Name, len(group.Videos))
+		}
+		for _, video := range group.Videos {
+			result, ok := allResults[video.ID]
+			status := "not processed"
+			switch {
+			case !ok:
+			case result.Err != nil:
+				status = "error: " + strings.ReplaceAll(result.Err.Error(), "\n", " ")
+			case result.Summary != "":
+				status = result.Summary
+			case result.TranscriptPath != "":
+				status = "transcript saved"
+			}
+			if placeholder != "" && (!ok || result.Summary == "") {
+				status = placeholder + " (" + status + ")"
+			}
+
+			title := strings.ReplaceAll(video.Title, "]", "\\]")
+			if ok && result.TranscriptPath != "" {
+				fmt.Fprintf(&builder, "- [%s](%s)", title, filepath.Base(result.TranscriptPath))
 			} else {
-				log.Printf("Video %s (%s): Successfully fetched transcript.", v.ID, v.Title)
-				minValLocal := func(a, b int) int {
-					if a < b {
-						return a
-					}
-					return b
-				}
-				log.Printf("  Transcript snippet for %s: %s...", v.ID, transcript[:minValLocal(100, len(transcript))])
-
-				if currentGeminiClient != nil {
-					log.Printf("  Video %s (%s): Attempting to summarize transcript...", v.ID, v.Title)
-					summary, summaryErr := summarizeTranscriptWithGemini(ctx, currentGeminiClient, transcript, currentCfg) // summaryErr
-					if summaryErr != nil {
-						log.Printf("  Video %s (%s): Error summarizing: %v", v.ID, v.Title, summaryErr)
-						currentProcessingResult.Err = summaryErr // Store error object
-					} else {
-						log.Printf("  Video %s (%s): Successfully summarized.", v.ID, v.Title)
-						currentProcessingResult.Summary = strings.TrimSpace(summary)
-						log.Printf("  Summary for %s: %s", v.ID, currentProcessingResult.Summary)
-					}
-				} else {
-					// Only set error if no other error has occurred yet for this video
-					if currentProcessingResult.Err == nil {
-						currentProcessingResult.Err = fmt.Errorf("summarization skipped (Gemini client not available)")
-					}
-					log.Printf("  Video %s (%s): Summarization skipped (Gemini client not available).", v.ID, v.Title)
+				fmt.Fprintf(&builder, "- %s", title)
+			}
+			if ok && result.ThumbnailPath != "" {
+				if rel, err := filepath.Rel(dir, result.ThumbnailPath); err == nil {
+					fmt.Fprintf(&builder, " ([thumbnail](%s))", filepath.ToSlash(rel))
 				}
 			}
-			resultsChannel <- currentProcessingResult
-		}(video, cfg, geminiClient)
+			fmt.Fprintf(&builder, " — %s\n", status)
+		}
+	}
+
+	path := filepath.Join(dir, transcriptIndexMarkdown)
+	if err := os.WriteFile(path, []byte(builder.String()), filePerm(perm)); err != nil {
+		return "", fmt.Errorf("failed to write transcript index %s: %w", path, err)
+	}
+	return path, nil
+}
+
+// printNearDuplicates reports clusters of videos whose summaries are at least
+// threshold similar. It only reads the collected results.
+func printNearDuplicates(w io.Writer, videos []VideoDetails, allResults map[string]ProcessingResult, threshold float64) {
+	var summarized []ProcessingResult
+	for _, video := range videos {
+		if result, ok := allResults[video.ID]; ok && result.Summary != "" {
+			summarized = append(summarized, result)
+		}
+	}
+	clusters := findNearDuplicateClusters(summarized, threshold)
+
+	fmt.Fprintf(w, "\n--- Near-Duplicate Summaries (similarity >= %.2f) ---\n", threshold)
+	if len(clusters) == 0 {
+		fmt.Fprintln(w, "None found.")
+		return
+	}
+	for i, cluster := range clusters {
+		fmt.Fprintf(w, "\nGroup %d (%d videos):\n", i+1, len(cluster))
+		for _, result := range cluster {
+			fmt.Fprintf(w, "  - %s: %s\n", result.VideoDetails.ID, result.VideoDetails.Title)
+		}
+	}
+	log.Printf("Found %d groups of near-duplicate summaries.", len(clusters))
+}
+
+// runStopReason describes why the run context ended early.
+func runStopReason(ctx context.Context) string {
+	if errors.Is(context.Cause(ctx), errStoppedOnFirstError) {
+		return "stopped after the first error"
+	}
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return "interrupted"
+	}
+	return "run timeout reached"
+}
+
+// reportTally counts what the per-video report entries showed, for the
+// totals logged at the end of the run.
+type reportTally struct {
+	successfulSummaries int
+	videosWithErrors    int
+	savedTranscripts    int
+	videosWithRetries   int
+	videosOverBudget    int
+	estimatedTokens     int
+}
+
+// printVideoReport writes one video's entry in the end-of-run report and
+// counts it in tally. ok is false when the video has no result.
+func printVideoReport(ctx context.Context, report io.Writer, colors reportColors, video VideoDetails, result ProcessingResult, ok bool, cfg *AppConfig, tally *reportTally) {
+	if !ok && ctx.Err() != nil {
+		fmt.Fprintf(report, "\nVideo ID: %s\nTitle: %s\n%s\n", video.ID, video.Title, colors.failure(fmt.Sprintf("Status/Error: Not processed (%s).", runStopReason(ctx))))
+		fmt.Fprintln(report, "------------------------------------")
+		tally.videosWithErrors++
+		return
+	}
+	if !ok {
+		log.Printf("CRITICAL: No processing result found for video ID %s, Title: %s.", video.ID, video.Title)
+		fmt.Fprintf(report, "\nVideo ID: %s\nTitle: %s\n%s\n", video.ID, video.Title, colors.failure("Status/Error: Result missing."))
+		fmt.Fprintln(report, "------------------------------------")
+		tally.videosWithErrors++
+		return
+	}
+
+	fmt.Fprintf(report, "\nVideo ID: %s\nTitle: %s\n", result.VideoDetails.ID, result.VideoDetails.Title)
+	if result.GeneratedTitle != "" {
+		fmt.Fprintf(report, "Generated Title: %s\n", result.GeneratedTitle)
+	}
+	if result.VideoDetails.SourceLabel != "" {
+		fmt.Fprintf(report, "Source: %s\n", result.VideoDetails.SourceLabel)
+	}
+	if result.VideoDetails.Position > 0 {
+		fmt.Fprintf(report, "Playlist Position: %d\n", result.VideoDetails.Position)
+	}
+	if result.Summary != "" {
+		notes := ""
+		if result.Truncated {
+			notes += ", truncated"
+		}
+		if result.DescriptionIncluded {
+			notes += ", with description"
+		}
+		if cfg.ContextText != "" {
+			notes += ", with context"
+		}
+		if result.ContextFrom != "" {
+			notes += ", follows " + result.ContextFrom
+		}
+		if cfg.SameLanguage && result.SummaryLanguage != "" {
+			notes += ", in " + result.SummaryLanguage
+		}
+		if cfg.Cite {
+			notes += fmt.Sprintf(", %d citations", result.Citations)
+		}
+		if result.LowQuality {
+			notes += ", low-quality transcript"
+		}
+		if len(result.UnsupportedClaims) > 0 {
+			notes += ", unverified claims"
+		}
+		if len(result.ModelSummaries) > 0 {
+			printModelSummaries(report, colors, result.ModelSummaries, cfg.PreviewWords)
+		} else if cfg.SummarySentences > 0 {
+			fmt.Fprintf(report, "%s %s\n", colors.success(fmt.Sprintf("Summary (%d of %d sentences%s):", countSentences(result.Summary), cfg.SummarySentences, notes)), previewText(result.Summary, cfg.PreviewWords))
+		} else {
+			fmt.Fprintf(report, "%s %s\n", colors.success(fmt.Sprintf("Summary (%d words%s):", summaryWordCount(result.VideoDetails, cfg), notes)), previewText(result.Summary, cfg.PreviewWords))
+		}
+		tally.successfulSummaries++
+	}
+	if result.SubtitleTrack != "" && baseLanguage(result.SubtitleTrack) != "en" {
+		fmt.Fprintf(report, "Subtitle track: %s\n", result.SubtitleTrack)
+	}
+	if result.Quality != nil {
+		fmt.Fprintf(report, "Transcript quality: %s\n", result.Quality)
+	}
+	if result.UnsupportedClaims != nil {
+		if len(result.UnsupportedClaims) == 0 {
+			fmt.Fprintln(report, "Verification: every claim is supported by the transcript")
+		} else {
+			fmt.Fprintln(report, colors.warning(fmt.Sprintf("Verification: %d unsupported claims:", len(result.UnsupportedClaims))))
+			for _, claim := range result.UnsupportedClaims {
+				fmt.Fprintf(report, "  - %s\n", claim)
+			}
+		}
+	}
+	if result.CommentsSummary != "" {
+		fmt.Fprintf(report, "Audience Sentiment: %s\n", previewText(result.CommentsSummary, cfg.PreviewWords))
+	}
+	if result.TranscriptPath != "" {
+		fmt.Fprintf(report, "Transcript: %s\n", result.TranscriptPath)
+		tally.savedTranscripts++
+	}
+	if result.NotesPath != "" {
+		fmt.Fprintf(report, "Notes: %s\n", result.NotesPath)
+	}
+	if result.EstimatedTokens > 0 {
+		fmt.Fprintf(report, "Estimated prompt tokens: %d\n", result.EstimatedTokens)
+		tally.estimatedTokens += result.EstimatedTokens
+	}
+	if result.TranscriptAttempts > 1 || result.LLMAttempts > 1 {
+		fmt.Fprintf(report, "Attempts: transcript %d, summary %d\n", result.TranscriptAttempts, result.LLMAttempts)
+		tally.videosWithRetries++
+	}
+	if errors.Is(result.Err, errBudgetExceeded) {
+		tally.videosOverBudget++
+	}
+	if cfg.Bare && result.Err == nil && result.Summary != "" {
+		fmt.Println(strings.Join(strings.Fields(result.Summary), " "))
+	}
+	if result.Err != nil { // Check if there was an error object
+		fmt.Fprintln(report, colors.failure(fmt.Sprintf("Status/Error: %v", result.Err))) // Print error using %v
+		tally.videosWithErrors++
+	} else if result.Summary == "" && !cfg.TranscriptsOnly && !cfg.Estimate { // No error, but also no summary
+		fmt.Fprintln(report, colors.warning("Status: No summary generated (e.g., transcript was empty or summarization skipped)."))
+	}
+	fmt.Fprintln(report, "------------------------------------")
+}
+
+// --- Main Application ---
+
+// main exits with the code returned by run, after run's deferred
+// cleanup (closing the log file, cancelling contexts) has happened.
+func main() {
+	os.Exit(run())
+}
+
+// run does the work of main and returns the process exit code.
+func run() int {
+	runStart := time.Now()
+	log.SetFlags(log.Ldate | log.Ltime | log.Lmicroseconds)
+	log.Printf("Application starting...")
+
+	loadEnvironmentFile()
+	cfg, err := initializeAppConfig()
+	if err != nil {
+		log.Printf("CRITICAL: Failed to initialize application configuration: %v", err)
+		return 1
+	}
+	closeLog, err := setupLogFile(cfg)
+	if err != nil {
+		log.Printf("CRITICAL: %v", err)
+		return 1
+	}
+	defer closeLog()
+
+	if cfg.CleanupOnStart {
+		if removed, err := purgeStaleTempFiles(cfg.TempTranscriptDir, time.Now()); err != nil {
+			log.Printf("Warning: %s", runWarnings.add(warnCleanup, "", "Could not clean up %s at startup: %v", cfg.TempTranscriptDir, err))
+		} else if removed > 0 {
+			log.Printf("Removed %d stale temp files from %s (-cleanup-on-start).", removed, cfg.TempTranscriptDir)
+		}
+	}
+
+	log.Printf("--- Application Configuration ---")
+	if cfg.LocalFile != "" {
+		log.Printf("Local File: %s", cfg.LocalFile)
+	} else if hasExplicitSources(cfg) {
+		log.Printf("Sources: %d playlist(s), %d channel(s), %d video(s), input file: %q", len(cfg.Playlists), len(cfg.Channels), len(cfg.VideoIDs), cfg.InputFile)
+	} else {
+		log.Printf("Playlist ID: %s", cfg.PlaylistID)
+	}
+	log.Printf("Gemini Model: %s", cfg.GeminiModel)
+	if cfg.SummarySentences > 0 {
+		log.Printf("Summary Sentence Count: %d", cfg.SummarySentences)
+	} else {
+		log.Printf("Summary Word Count: %d", cfg.SummaryWordCount)
+	}
+	if cfg.Audience != "" {
+		log.Printf("Audience: %s", cfg.Audience)
+	}
+	log.Printf("Concurrency Limit: %d (min %d under rate limiting)", cfg.ConcurrencyLimit, cfg.MinConcurrency)
+	log.Printf("HTTP Timeout: %v", cfg.HTTPTimeout)
+	youtubeKeyStatus := "NOT LOADED"
+	if len(cfg.YoutubeAPIKeys) > 0 {
+		youtubeKeyStatus = fmt.Sprintf("LOADED (%d key(s))", len(cfg.YoutubeAPIKeys))
+	}
+	log.Printf("YouTube API Key: [%s]", youtubeKeyStatus)
+	geminiKeyStatus := "NOT LOADED - Summarization will be skipped"
+	if len(cfg.GeminiAPIKeys) > 0 {
+		geminiKeyStatus = fmt.Sprintf("LOADED (%d key(s))", len(cfg.GeminiAPIKeys))
+	}
+	log.Printf("Gemini API Key: [%s]", geminiKeyStatus)
+	if cfg.Usage.maxCost > 0 {
+		log.Printf("Cost Budget: $%.2f (input $%g, output $%g per 1K tokens)", cfg.Usage.maxCost, cfg.Usage.inputPricePer1K, cfg.Usage.outputPricePer1K)
+	}
+	if cfg.PositionRange.set() {
+		log.Printf("Playlist Positions: %s", cfg.PositionRange)
+	}
+	if cfg.RetryOn != nil {
+		log.Printf("yt-dlp Retry On: %s", strings.Join(cfg.RetryOn, ", "))
+	}
+	if cfg.SummaryPrefix != nil {
+		log.Printf("Summary Prefix: %q", cfg.SummaryPrefix.Root.String())
+	}
+	if cfg.SummarySuffix != nil {
+		log.Printf("Summary Suffix: %q", cfg.SummarySuffix.Root.String())
+	}
+	if cfg.OutputRate > 0 {
+		log.Printf("Output Rate: %g results/s (burst %d)", cfg.OutputRate, cfg.OutputBurst)
+	}
+	if cfg.Verify {
+		log.Printf("Verify: enabled (one extra Gemini call per summary; transcripts over %d characters are skipped)", cfg.VerifyMaxChars)
+	}
+	if cfg.SeriesContext {
+		log.Printf("Series Context: enabled (videos are processed one at a time)")
+	}
+	if cfg.ChannelLimit > 0 {
+		log.Printf("Channel Limit: newest %d uploads per channel", cfg.ChannelLimit)
+	}
+	if cfg.StreamSubtitles {
+		if streamingSubtitles(cfg) {
+			log.Printf("Stream Subtitles: enabled")
+		} else {
+			log.Printf("Warning: %s", runWarnings.add(warnConfig, "", "-stream-subtitles has no effect with -cite, -preserve-speakers, -compact or -skip-sponsors, which need the parsed cues."))
+		}
+	}
+	if cfg.Structured {
+		log.Printf("Structured Summaries: enabled (title, one_liner, key_points, topics, sentiment)")
+	}
+	if cfg.ManifestPath != "" {
+		log.Printf("Manifest: %s", cfg.ManifestPath)
+	}
+	if cfg.PauseFile != "" {
+		log.Printf("Pause File: %s (create it to pause, delete it to resume)", cfg.PauseFile)
+	}
+	if cfg.Usage.maxChars > 0 {
+		log.Printf("Transcript Budget: %d characters", cfg.Usage.maxChars)
+	}
+	if cfg.YtDlpProxies != nil {
+		log.Printf("yt-dlp: rotating through %d proxy(ies)", len(cfg.YtDlpProxies.proxies))
+	}
+	if cfg.YtDlpUserAgent != "" || len(cfg.YtDlpHeaders) > 0 {
+		log.Printf("yt-dlp: custom user agent set: %t, extra headers: %d (values not logged)", cfg.YtDlpUserAgent != "", len(cfg.YtDlpHeaders))
+	}
+	log.Println("-------------------------------")
+
+	if cfg.Doctor {
+		if !runDoctor(context.Background(), cfg) {
+			return 1
+		}
+		return 0
+	}
+
+	transcriptSource, err := newTranscriptSource(cfg)
+	if err != nil {
+		log.Printf("CRITICAL: Invalid transcript source: %v", err)
+		return 1
+	}
+
+	// SIGINT or SIGTERM cancels the run: workers stop, the report is written
+	// for the videos done so far and deferred cleanup runs. A second signal
+	// kills the process as usual.
+	ctx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+	go func(signalCtx context.Context) {
+		<-signalCtx.Done()
+		stopSignals()
+	}(ctx)
+	if cfg.RunTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.RunTimeout)
+		defer cancel()
+		log.Printf("Run timeout: %v", cfg.RunTimeout)
+	}
+	ctx, stopRun := context.WithCancelCause(ctx)
+	defer stopRun(nil)
+	runTracer := newTracer(cfg.OTelEndpoint, cfg.HTTPTimeout)
+	ctx, runSpan := runTracer.start(ctx, "summify.run")
+	var geminiClient *rotatingGeminiModel
+	if cfg.TranscriptsOnly {
+		log.Printf("Transcripts-only mode: summarization disabled, transcripts will be saved to %s.", cfg.KeepTranscriptsDir)
+	} else if cfg.Estimate {
+		log.Printf("Estimate mode: prompts are built and sized but Gemini is not called.")
+	} else if len(cfg.GeminiAPIKeys) > 0 {
+		client, errClient := newGeminiModel(ctx, cfg.GeminiAPIKeys, cfg.GeminiModel, cfg.HTTPTimeout, cfg.MaxRetryAfter) // Renamed err to errClient
+		if errClient != nil {
+			log.Printf("Warning: %s", runWarnings.add(warnConfig, "", "Failed to create Gemini client (key was present): %v. Summarization will be skipped.", errClient))
+		} else {
+			if err := validateGeminiModel(ctx, client.client, cfg.GeminiModel); err != nil {
+				log.Printf("CRITICAL: Invalid Gemini model configuration: %v", err)
+				return 1
+			}
+			geminiClient = client
+			log.Printf("Successfully initialized Gemini client with model %s.", cfg.GeminiModel)
+		}
+	}
+	var comparedModels []comparedModel
+	if geminiClient != nil && len(cfg.CompareModels) > 0 {
+		comparedModels, err = newComparedModels(ctx, cfg)
+		if err != nil {
+			log.Printf("CRITICAL: Invalid -compare-models: %v", err)
+			return 1
+		}
+		log.Printf("Comparing models: %s (each video is summarized %d times).", strings.Join(cfg.CompareModels, ", "), len(comparedModels))
+	}
+
+	var youtubeService *rotatingYouTubeService
+	playlistTitles := newPlaylistTitleCache()
+	skippedItems := make(skippedPlaylistItems)
+	var videos []VideoDetails
+	if cfg.LocalFile != "" {
+		log.Printf("Summarizing local file %s; YouTube and yt-dlp will not be used.", cfg.LocalFile)
+		videos = []VideoDetails{localFileVideo(cfg.LocalFile)}
+	} else if cfg.FromTranscriptsDir != "" {
+		log.Printf("Reading stored transcripts from %s; YouTube and yt-dlp will not be used.", cfg.FromTranscriptsDir)
+		videos, err = listStoredTranscripts(cfg.FromTranscriptsDir)
+		if err != nil {
+			log.Printf("CRITICAL: Failed to list stored transcripts: %v", err)
+			return 1
+		}
+		if len(videos) == 0 {
+			log.Printf("No transcripts found in %s. Exiting.", cfg.FromTranscriptsDir)
+			return 0
+		}
+	} else {
+		youtubeService, err = newRotatingYouTubeService(ctx, cfg.YoutubeAPIKeys, cfg.HTTPTimeout, cfg.MaxRetryAfter)
+		if err != nil {
+			log.Printf("CRITICAL: Failed to create YouTube service: %v", err)
+			return 1
+		}
+		log.Printf("Successfully initialized YouTube service.")
+
+		videos, err = collectVideos(ctx, youtubeService, cfg, playlistTitles, skippedItems)
+		if err != nil {
+			log.Printf("CRITICAL: Failed to fetch video details: %v", err)
+			return 1
+		}
+		if len(videos) == 0 {
+			log.Printf("No videos found. Exiting.")
+			return 0
+		}
+	}
+	if cfg.TitleMatch != nil || cfg.TitleExclude != nil {
+		total := len(videos)
+		videos = filterByTitle(videos, cfg.TitleMatch, cfg.TitleExclude)
+		log.Printf("Kept %d of %d videos after the title filters.", len(videos), total)
+		if len(videos) == 0 {
+			log.Printf("No videos match the title filters. Exiting.")
+			return 0
+		}
+	}
+	if cfg.Order != "" {
+		orderVideos(videos, cfg.Order)
+		log.Printf("Ordered %d videos by %s.", len(videos), cfg.Order)
+	}
+
+	var chain *seriesChain
+	if cfg.SeriesContext {
+		chain = newSeriesChain(videos)
+	}
+	var appendLog *resultLog
+	if cfg.AppendJSONL != "" {
+		records, err := readResultLog(cfg.AppendJSONL)
+		if err != nil {
+			log.Printf("CRITICAL: %v", err)
+			return 1
+		}
+		chain.seed(records)
+		var done int
+		videos, done = skipLoggedVideos(videos, records, cfg.TranscriptsOnly)
+		if done > 0 {
+			log.Printf("Skipping %d videos already done in %s.", done, cfg.AppendJSONL)
+		}
+		if len(videos) == 0 {
+			log.Printf("All videos are already done in %s. Exiting.", cfg.AppendJSONL)
+			return 0
+		}
+		appendLog, err = openResultLog(cfg)
+		if err != nil {
+			log.Printf("CRITICAL: %v", err)
+			return 1
+		}
+		defer appendLog.Close()
+	}
+	var publishQueue *resultQueue
+	if cfg.PublishURL != "" {
+		publisher, err := newResultPublisher(cfg.PublishURL)
+		if err != nil {
+			log.Printf("CRITICAL: %v", err)
+			return 1
+		}
+		var rate *tokenBucket
+		if cfg.OutputRate > 0 {
+			rate = newTokenBucket(cfg.OutputRate, cfg.OutputBurst)
+		}
+		publishQueue = newResultQueue(publisher, cfg, rate)
+		log.Printf("Publishing results to %s on %q.", redactPublishURL(cfg.PublishURL), cfg.PublishTopic)
+	}
+
+	if cfg.ConfirmThreshold > 0 && len(videos) > cfg.ConfirmThreshold && !cfg.AssumeYes {
+		if !stdinIsTerminal() {
+			log.Printf("Processing %d videos (over -confirm-over %d) without confirmation: stdin is not a terminal.", len(videos), cfg.ConfirmThreshold)
+		} else if !confirmLargeRun(os.Stdin, os.Stderr, len(videos), cfg) {
+			log.Printf("Run cancelled at confirmation prompt. Exiting.")
+			return 0
+		}
+	}
+
+	log.Printf("--- Processing %d Videos Concurrently (Limit: %d) ---", len(videos), cfg.ConcurrencyLimit)
+
+	worker := &videoWorker{
+		source:          transcriptSource,
+		youtube:         youtubeService,
+		comparedModels:  comparedModels,
+		tracer:          runTracer,
+		chain:           chain,
+		thumbnailClient: &http.Client{Timeout: cfg.HTTPTimeout},
+	}
+
+	var wg sync.WaitGroup
+	// resultsChannel now carries ProcessingResult
+	resultsChannel := make(chan ProcessingResult, len(videos))
+	limiter := newConcurrencyLimiter(cfg.MinConcurrency, cfg.ConcurrencyLimit)
+	worker.limiter = limiter
+
+	// stopOnHardError cancels the run for -stop-on-first-error when result
+	// failed for a reason other than having nothing to summarize.
+	var stopOnce sync.Once
+	stopOnHardError := func(result ProcessingResult) {
+		if !cfg.StopOnFirstError || result.Err == nil || errors.Is(result.Err, errSkipped) {
+			return
+		}
+		stopOnce.Do(func() {
+			log.Printf("Video %s (%s): Stopping the run after this error (-stop-on-first-error).", result.VideoDetails.ID, result.VideoDetails.Title)
+			stopRun(errStoppedOnFirstError)
+		})
+	}
+
+	var pause *pauseControl
+	if cfg.PauseFile != "" {
+		pause = newPauseControl(ctx, cfg.PauseFile)
+	}
+	for i, video := range videos { // video is VideoDetails
+		if ctx.Err() == nil {
+			pause.wait(ctx)
+			limiter.acquire(ctx)
+		}
+		if ctx.Err() != nil {
+			log.Printf("%s: not starting the remaining %d videos.", runStopReason(ctx), len(videos)-i)
+			break
+		}
+		wg.Add(1)
+
+		go func(v VideoDetails) {
+			defer wg.Done()
+			defer limiter.release()
+			result := worker.process(ctx, v, cfg, geminiClient)
+			stopOnHardError(result)
+			resultsChannel <- result
+		}(video)
 	}
 
 	go func() {
@@ -386,46 +2226,187 @@ func main() {
 
 	// Results collection needs to handle ProcessingResult
 	allResults := make(map[string]ProcessingResult)
+	var reorderer *resultReorderer
+	if cfg.OrderedStream {
+		reorderer = newResultReorderer(videos)
+	}
 	for result := range resultsChannel {
 		allResults[result.VideoDetails.ID] = result // Use VideoDetails.ID
-	}
-
-	fmt.Println("\n\n--- All Video Summaries (Processed Concurrently) ---")
-	successfulSummaries := 0
-	videosWithErrors := 0 // Simplified error count
-
-	// Iterate original video list for order
-	for _, video := range videos { // video is VideoDetails
-		result, ok := allResults[video.ID]
-		if !ok {
-			log.Printf("CRITICAL: No processing result found for video ID %s, Title: %s.", video.ID, video.Title)
-			fmt.Printf("\nVideo ID: %s\nTitle: %s\nStatus/Error: Result missing.\n", video.ID, video.Title)
-			fmt.Println("------------------------------------")
-			videosWithErrors++
+		if publishQueue != nil {
+			publishQueue.add(result)
+		}
+		if appendLog == nil {
 			continue
 		}
+		ready := []ProcessingResult{result}
+		if reorderer != nil {
+			ready = reorderer.add(result)
+		}
+		for _, r := range ready {
+			if err := appendLog.append(r); err != nil {
+				log.Printf("Warning: %s", runWarnings.add(warnOutput, "", "%v", err))
+			}
+		}
+	}
+	if reorderer != nil {
+		for _, r := range reorderer.flush() {
+			if err := appendLog.append(r); err != nil {
+				log.Printf("Warning: %s", runWarnings.add(warnOutput, "", "%v", err))
+			}
+		}
+	}
+	if publishQueue != nil {
+		published, err := publishQueue.close()
+		if err != nil {
+			log.Printf("Warning: %s", runWarnings.add(warnOutput, "", "%v", err))
+		}
+		log.Printf("Published %d results to %q.", published, cfg.PublishTopic)
+	}
 
-		fmt.Printf("\nVideo ID: %s\nTitle: %s\n", result.VideoDetails.ID, result.VideoDetails.Title)
-		if result.Summary != "" {
-			fmt.Printf("Summary (%d words): %s\n", cfg.SummaryWordCount, result.Summary)
-			successfulSummaries++
+	var vectors map[string][]float32
+	if cfg.EmbeddingsPath != "" {
+		if geminiClient == nil {
+			log.Printf("Warning: %s", runWarnings.add(warnOutput, "", "Skipping -embeddings: Gemini client is not available."))
+		} else {
+			vectors = embedSummaries(ctx, geminiClient, videos, allResults, cfg)
+			if written, err := writeSummaryEmbeddings(cfg.EmbeddingsPath, videos, vectors, cfg); err != nil {
+				log.Printf("Warning: %s", runWarnings.add(warnOutput, "", "Failed to write summary embeddings: %v", err))
+			} else {
+				log.Printf("Wrote %d summary embeddings (%s) to %s.", written, cfg.EmbeddingModel, cfg.EmbeddingsPath)
+			}
 		}
-		if result.Err != nil { // Check if there was an error object
-			fmt.Printf("Status/Error: %v\n", result.Err) // Print error using %v
-			videosWithErrors++
-		} else if result.Summary == "" { // No error, but also no summary
-			fmt.Println("Status: No summary generated (e.g., transcript was empty or summarization skipped).")
+	}
+	// Embeddings, -dedupe and the manifest use the raw summaries; everything
+	// written out carries the -summary-prefix and -summary-suffix text.
+	outputResults := wrapResults(allResults, cfg)
+	if cfg.ExportFormat != "" {
+		if written, err := writeVectorExport(cfg.ExportPath, cfg.ExportFormat, videos, outputResults, vectors, cfg.TempPerms); err != nil {
+			log.Printf("Warning: %s", runWarnings.add(warnOutput, "", "Failed to write %s export: %v", cfg.ExportFormat, err))
+		} else {
+			log.Printf("Wrote %d %s records to %s (vectors included: %t).", written, cfg.ExportFormat, cfg.ExportPath, vectors != nil)
+		}
+	}
+	if cfg.CSVPath != "" {
+		if written, err := writeResultsCSV(cfg.CSVPath, cfg.CSVColumns, videos, outputResults, cfg.EmptyPlaceholder, cfg.TempPerms); err != nil {
+			log.Printf("Warning: %s", runWarnings.add(warnOutput, "", "%v", err))
+		} else {
+			log.Printf("Wrote %d CSV rows to %s.", written, cfg.CSVPath)
 		}
-		fmt.Println("------------------------------------")
 	}
-	fmt.Println("\n--- End of Summaries ---")
-	log.Printf("Processing complete. Successful summaries: %d, Videos with errors/no summary: %d, Total videos: %d",
-		successfulSummaries, videosWithErrors, len(videos))
 
-	if err := os.RemoveAll(cfg.TempTranscriptDir); err != nil {
-		log.Printf("Warning: Failed to remove temporary transcript directory %s: %v", cfg.TempTranscriptDir, err)
+	// With -bare only the summaries go to stdout; the decorated report goes
+	// to stderr instead.
+	var report io.Writer = os.Stdout
+	if cfg.Bare {
+		report = os.Stderr
+	}
+	if cfg.StatsOnly {
+		report = io.Discard
+	}
+	colors := newReportColors(report, cfg)
+	fmt.Fprintln(report, "\n\n--- All Video Summaries (Processed Concurrently) ---")
+	fmt.Fprintf(report, "From %s\n", describeSources(cfg, playlistTitles))
+	if cfg.Audience != "" {
+		fmt.Fprintf(report, "Audience: %s\n", cfg.Audience)
+	}
+	if skippedItems.total() > 0 {
+		fmt.Fprintf(report, "Skipped %d playlist items: %s\n", skippedItems.total(), skippedItems)
+	}
+	var tally reportTally
+
+	// Iterate original video list for order, in -group-by sections when set
+	groups := groupVideos(videos, allResults, cfg, playlistTitles)
+	for _, group := range groups {
+		if group.Name != "" {
+			fmt.Fprintf(report, "\n=== %s (%d) ===\n", group.Name, len(group.Videos))
+		}
+		for _, video := range group.Videos { // video is VideoDetails
+			result, ok := outputResults[video.ID]
+			printVideoReport(ctx, report, colors, video, result, ok, cfg, &tally)
+		}
+	}
+	if cfg.DedupeThreshold > 0 {
+		printNearDuplicates(report, videos, allResults, cfg.DedupeThreshold)
+	}
+	if cfg.Estimate {
+		fmt.Fprintf(report, "\nEstimated input tokens: %d in total (about %d characters per token)\n", tally.estimatedTokens, charsPerTokenEstimate)
+	}
+	// Everything that can still add a warning runs before the Warnings
+	// section is printed, so the section is complete.
+	if cfg.KeepTranscriptsDir != "" && tally.savedTranscripts > 0 {
+		if indexPath, err := writeTranscriptIndex(cfg.KeepTranscriptsDir, describeSources(cfg, playlistTitles), groups, outputResults, cfg.EmptyPlaceholder, time.Now(), cfg.TempPerms); err != nil {
+			log.Printf("Warning: %s", runWarnings.add(warnOutput, "", "%v", err))
+		} else {
+			log.Printf("Wrote transcript index to %s.", indexPath)
+		}
+	}
+	if cfg.NoCleanup {
+		log.Printf("Cleanup disabled (-no-cleanup): subtitle files left in %s", cfg.TempTranscriptDir)
+	} else if err := removeTempDir(cfg.TempTranscriptDir); err != nil {
+		log.Printf("Warning: %s", runWarnings.add(warnCleanup, "", "Failed to remove temporary transcript directory %s: %v", cfg.TempTranscriptDir, err))
 	} else {
 		log.Printf("Successfully removed temporary transcript directory: %s", cfg.TempTranscriptDir)
 	}
+	runSpan.setAttribute("summify.videos", len(videos))
+	runSpan.setAttribute("summify.successful_summaries", tally.successfulSummaries)
+	runSpan.end()
+	if err := runTracer.flush(context.Background()); err != nil {
+		log.Printf("Warning: %s", runWarnings.add(warnOutput, "", "%v", err))
+	} else if runTracer != nil {
+		log.Printf("Exported trace spans to %s.", runTracer.url)
+	}
+	if cfg.ManifestPath != "" {
+		if count, err := writeManifest(cfg.ManifestPath, effectiveConfig(), videos, allResults, time.Now(), cfg.TempPerms); err != nil {
+			log.Printf("Warning: %s", runWarnings.add(warnOutput, "", "%v", err))
+		} else {
+			log.Printf("Wrote a manifest of %d videos to %s.", count, cfg.ManifestPath)
+		}
+	}
+	printWarnings(report, colors, runWarnings.list())
+	fmt.Fprintln(report, "\n--- End of Summaries ---")
+	if cfg.StatsOnly {
+		printRunStats(os.Stdout, videos, allResults, cfg.Usage, time.Since(runStart))
+	}
+	if cfg.Usage.maxCost > 0 {
+		log.Printf("Estimated Gemini spend: $%.4f of the $%.2f budget; %d videos skipped due to -max-cost.", cfg.Usage.spent(), cfg.Usage.maxCost, tally.videosOverBudget)
+	}
+	if cfg.Usage.maxChars > 0 {
+		sent, skipped := cfg.Usage.charsSent()
+		log.Printf("Transcript characters sent: %d of the %d budget; %d videos skipped due to -max-total-chars.", sent, cfg.Usage.maxChars, skipped)
+	}
+	if tally.videosWithRetries > 0 {
+		log.Printf("%d videos needed retries (see Attempts in the report).", tally.videosWithRetries)
+	}
+	if cfg.TranscriptsOnly {
+		log.Printf("Processing complete. Transcripts saved: %d, Transcripts missing: %d, Total videos: %d",
+			tally.savedTranscripts, len(videos)-tally.savedTranscripts, len(videos))
+	} else {
+		log.Printf("Processing complete. Successful summaries: %d, Videos with errors/no summary: %d, Total videos: %d",
+			tally.successfulSummaries, tally.videosWithErrors, len(videos))
+	}
+	if cfg.WarningsJSON != "" {
+		warnings := runWarnings.list()
+		if err := writeWarningsJSON(cfg.WarningsJSON, warnings, cfg.TempPerms); err != nil {
+			log.Printf("Warning: %s", runWarnings.add(warnOutput, "", "%v", err))
+		} else {
+			log.Printf("Wrote %d warnings to %s.", len(warnings), cfg.WarningsJSON)
+		}
+	}
+	if peak, ok := peakMemory(); ok {
+		log.Printf("Peak memory use: %d MiB.", peak/(1024*1024))
+	}
 	log.Printf("Application finished in %v.", time.Since(runStart))
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		log.Printf("Run timeout of %v was reached; exiting with code %d.", cfg.RunTimeout, exitCodeRunTimeout)
+		return exitCodeRunTimeout
+	}
+	if errors.Is(context.Cause(ctx), errStoppedOnFirstError) {
+		log.Printf("Run was stopped after the first error; exiting with code %d.", exitCodeStoppedOnError)
+		return exitCodeStoppedOnError
+	}
+	if errors.Is(ctx.Err(), context.Canceled) {
+		log.Printf("Run was interrupted; exiting with code %d.", exitCodeInterrupted)
+		return exitCodeInterrupted
+	}
+	return 0
 }
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+func TestCandidateText(t *testing.T) {
+	tests := []struct {
+		name      string
+		candidate *genai.Candidate
+		wantText  string
+		wantOK    bool
+	}{
+		{"nil content", &genai.Candidate{}, "", false},
+		{"zero parts", &genai.Candidate{Content: &genai.Content{}}, "", false},
+		{"one text part", &genai.Candidate{Content: &genai.Content{Parts: []genai.Part{genai.Text(" A summary. ")}}}, "A summary.", true},
+		{"several text parts", &genai.Candidate{Content: &genai.Content{Parts: []genai.Part{genai.Text("First half, "), genai.Text("second half.")}}}, "First half, second half.", true},
+		{"text and other parts", &genai.Candidate{Content: &genai.Content{Parts: []genai.Part{
+			genai.FunctionCall{Name: "lookup"},
+			genai.Text("Only this."),
+			genai.Blob{MIMEType: "image/png", Data: []byte{1}},
+		}}}, "Only this.", true},
+		{"only other parts", &genai.Candidate{Content: &genai.Content{Parts: []genai.Part{genai.Blob{MIMEType: "image/png"}}}}, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, ok := candidateText(tt.candidate)
+			if text != tt.wantText || ok != tt.wantOK {
+				t.Errorf("candidateText() = %q, %v, want %q, %v", text, ok, tt.wantText, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// --- Run Manifest ---
+
+// manifestEntry records what went into one video's summary for -manifest:
+// where the transcript came from and SHA-256 checksums of the transcript,
+// each prompt sent and each summary returned.
+type manifestEntry struct {
+	VideoID          string          `json:"video_id"`
+	Title            string          `json:"title"`
+	PlaylistPosition int64           `json:"playlist_position,omitempty"`
+	TranscriptSource string          `json:"transcript_source"`
+	TranscriptSHA256 string          `json:"transcript_sha256,omitempty"`
+	Models           []manifestModel `json:"models,omitempty"`
+	StartedAt        time.Time       `json:"started_at"`
+	FinishedAt       time.Time       `json:"finished_at"`
+	Error            string          `json:"error,omitempty"`
+}
+
+// manifestModel is one model's prompt and summary checksums. There is one
+// per -compare-models model, or a single one for -model.
+type manifestModel struct {
+	Model         string `json:"model"`
+	PromptSHA256  string `json:"prompt_sha256,omitempty"`
+	SummarySHA256 string `json:"summary_sha256,omitempty"`
+}
+
+// runManifest is the document written by -manifest.
+type runManifest struct {
+	GeneratedAt time.Time         `json:"generated_at"`
+	Config      map[string]string `json:"config"`
+	Videos      []manifestEntry   `json:"videos"`
+}
+
+// sha256Hex returns the hex-encoded SHA-256 of text.
+func sha256Hex(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// transcriptSourceName describes a transcript source for the manifest.
+func transcriptSourceName(source TranscriptSource) string {
+	switch s := source.(type) {
+	case ytDlpTranscriptSource:
+		return transcriptSourceYtDlp
+	case fileTranscriptSource:
+		return transcriptSourceFiles + ":" + s.dir
+	case localFileTranscriptSource:
+		return "local-file:" + s.path
+	default:
+		return fmt.Sprintf("%T", source)
+	}
+}
+
+// effectiveConfig returns every flag's value after parsing, defaults
+// included, with credentials masked: userinfo in proxy, publish and
+// collector URLs, and -add-header values.
+func effectiveConfig() map[string]string {
+	config := make(map[string]string)
+	flag.VisitAll(func(f *flag.Flag) {
+		value := f.Value.String()
+		switch f.Name {
+		case "proxy":
+			var proxies []string
+			for _, proxy := range splitCommaList(value) {
+				proxies = append(proxies, redactProxy(proxy))
+			}
+			value = strings.Join(proxies, ",")
+		case "publish-url", "otel-endpoint":
+			if value != "" {
+				value = redactPublishURL(value)
+			}
+		case "add-header":
+			if list, ok := f.Value.(*stringListFlag); ok {
+				var headers []string
+				for _, header := range *list {
+					name, _, _ := strings.Cut(header, ":")
+					headers = append(headers, name+":<redacted>")
+				}
+				value = strings.Join(headers, ", ")
+			}
+		}
+		config[f.Name] = value
+	})
+	return config
+}
+
+// writeManifest writes the -manifest file: the effective configuration
+// followed by an entry for each processed video, in playlist order.
+func writeManifest(path string, config map[string]string, videos []VideoDetails, allResults map[string]ProcessingResult, generated time.Time, perm os.FileMode) (int, error) {
+	manifest := runManifest{GeneratedAt: generated, Config: config, Videos: []manifestEntry{}}
+	for _, video := range videos {
+		result, ok := allResults[video.ID]
+		if !ok || result.Manifest == nil {
+			continue
+		}
+		entry := *result.Manifest
+		entry.PlaylistPosition = video.Position
+		entry.FinishedAt = entry.StartedAt.Add(result.Elapsed)
+		if len(result.ModelSummaries) > 0 {
+			for i := range entry.Models {
+				if i < len(result.ModelSummaries) && result.ModelSummaries[i].Summary != "" {
+					entry.Models[i].SummarySHA256 = sha256Hex(result.ModelSummaries[i].Summary)
+				}
+			}
+		} else if len(entry.Models) == 1 && result.Summary != "" {
+			entry.Models[0].SummarySHA256 = sha256Hex(result.Summary)
+		}
+		if result.Err != nil {
+			entry.Error = result.Err.Error()
+		}
+		manifest.Videos = append(manifest.Videos, entry)
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), filePerm(perm)); err != nil {
+		return 0, fmt.Errorf("failed to write manifest %s: %w", path, err)
+	}
+	return len(manifest.Videos), nil
+}
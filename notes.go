@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// --- Study Notes ---
+
+const maxNoteFileNameRunes = 80
+
+// noteFileName builds a file-system-safe Markdown file name from a video
+// title, keeping the ID so that videos with the same title do not collide.
+func noteFileName(title, videoID string) string {
+	var builder strings.Builder
+	dash := false
+	for _, r := range title {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			builder.WriteRune(r)
+			dash = false
+		} else if !dash && builder.Len() > 0 {
+			builder.WriteRune('-')
+			dash = true
+		}
+	}
+	name := strings.TrimRight(builder.String(), "-")
+	if runes := []rune(name); len(runes) > maxNoteFileNameRunes {
+		name = strings.TrimRight(string(runes[:maxNoteFileNameRunes]), "-")
+	}
+	if name == "" {
+		return videoID + ".md"
+	}
+	return name + "-" + videoID + ".md"
+}
+
+// writeStudyNote writes one Markdown file with the video's title, link and
+// summary, followed by the full transcript in a collapsible section unless
+// includeTranscript is false. A -retitle title, when given, heads the note
+// above the original title and, with titleFileName, also names the file.
+func writeStudyNote(dir string, video VideoDetails, generatedTitle, summary, transcript string, includeTranscript, titleFileName bool, perm os.FileMode) (string, error) {
+	if err := makeDir(dir, perm); err != nil {
+		return "", fmt.Errorf("failed to create notes dir %s: %w", dir, err)
+	}
+
+	var builder strings.Builder
+	if generatedTitle != "" {
+		fmt.Fprintf(&builder, "# %s\n\nOriginal title: %s\n\n", generatedTitle, video.Title)
+	} else {
+		fmt.Fprintf(&builder, "# %s\n\n", video.Title)
+	}
+	if video.Source != "local-file" {
+		fmt.Fprintf(&builder, "<https://www.youtube.com/watch?v=%s>\n\n", video.ID)
+	}
+	fmt.Fprintf(&builder, "## Summary\n\n%s\n", summary)
+	if includeTranscript && transcript != "" {
+		fmt.Fprintf(&builder, "\n## Transcript\n\n<details>\n<summary>Full transcript</summary>\n\n%s\n\n</details>\n", html.EscapeString(transcript))
+	}
+
+	fileName := noteFileName(video.Title, video.ID)
+	if titleFileName && generatedTitle != "" {
+		fileName = noteFileName(generatedTitle, video.ID)
+	}
+	path := filepath.Join(dir, fileName)
+	if err := os.WriteFile(path, []byte(builder.String()), filePerm(perm)); err != nil {
+		return "", fmt.Errorf("failed to write notes for video %s: %w", video.ID, err)
+	}
+	return path, nil
+}
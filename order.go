@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// --- Processing Order ---
+
+// Values accepted by -order.
+const (
+	orderPlaylist = "playlist"
+	orderOldest   = "oldest"
+	orderNewest   = "newest"
+)
+
+func validateOrder(order string) error {
+	switch order {
+	case "", orderPlaylist, orderOldest, orderNewest:
+		return nil
+	default:
+		return fmt.Errorf("invalid -order %q: must be %s, %s or %s", order, orderPlaylist, orderOldest, orderNewest)
+	}
+}
+
+// orderVideos sorts videos in place for -order. oldest and newest sort by
+// publish date, with undated videos last; playlist sorts each playlist's
+// videos by their position in it, keeping sources in the order they were
+// gathered. Ties keep their current order.
+func orderVideos(videos []VideoDetails, order string) {
+	switch order {
+	case orderOldest, orderNewest:
+		sort.SliceStable(videos, func(i, j int) bool {
+			a, b := videos[i].PublishedAt, videos[j].PublishedAt
+			if a.IsZero() || b.IsZero() {
+				return !a.IsZero() && b.IsZero()
+			}
+			if order == orderOldest {
+				return a.Before(b)
+			}
+			return a.After(b)
+		})
+	case orderPlaylist:
+		sourceIndex := make(map[string]int)
+		for _, video := range videos {
+			if _, ok := sourceIndex[video.Source]; !ok {
+				sourceIndex[video.Source] = len(sourceIndex)
+			}
+		}
+		sort.SliceStable(videos, func(i, j int) bool {
+			a, b := videos[i], videos[j]
+			if a.Source != b.Source {
+				return sourceIndex[a.Source] < sourceIndex[b.Source]
+			}
+			return a.Position < b.Position
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// --- Pause Control File ---
+
+const pauseFilePollInterval = time.Second
+
+// pauseControl pauses the run while the -pause-file exists: videos already
+// started finish, but no new ones start until the file is deleted. A nil
+// pauseControl never pauses.
+type pauseControl struct {
+	path   string
+	paused atomic.Bool
+}
+
+// newPauseControl checks path once, so a run started with the file in place
+// begins paused, and then keeps watching it until ctx ends.
+func newPauseControl(ctx context.Context, path string) *pauseControl {
+	p := &pauseControl{path: path}
+	p.check()
+	go func() {
+		ticker := time.NewTicker(pauseFilePollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				p.check()
+			}
+		}
+	}()
+	return p
+}
+
+// check updates the paused flag from the control file, logging transitions.
+func (p *pauseControl) check() {
+	_, err := os.Stat(p.path)
+	exists := err == nil
+	if p.paused.Swap(exists) == exists {
+		return
+	}
+	if exists {
+		log.Printf("Paused: %s exists. Videos in progress will finish; delete the file to resume.", p.path)
+	} else {
+		log.Printf("Resumed: %s was removed.", p.path)
+	}
+}
+
+// wait blocks while the run is paused. It returns ctx's error if ctx ends
+// first.
+func (p *pauseControl) wait(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+	for p.paused.Load() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(pauseFilePollInterval):
+		}
+	}
+	return nil
+}
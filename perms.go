@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// --- File Permissions ---
+
+const defaultTempPerms os.FileMode = 0755
+
+// parseTempPerms reads an octal -temp-perms value. The owner must keep full
+// access, since Summify writes into and lists these directories.
+func parseTempPerms(value string) (os.FileMode, error) {
+	perm, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || perm > 0777 {
+		return 0, fmt.Errorf("invalid -temp-perms %q: must be an octal mode such as 0700", value)
+	}
+	if perm&0700 != 0700 {
+		return 0, fmt.Errorf("invalid -temp-perms %q: the owner needs read, write and execute (7xx)", value)
+	}
+	return os.FileMode(perm), nil
+}
+
+// makeDir creates dir with perm. A directory that already exists is
+// tightened to perm too, unless perm is the default.
+func makeDir(dir string, perm os.FileMode) error {
+	if err := os.MkdirAll(dir, perm); err != nil {
+		return err
+	}
+	if perm != defaultTempPerms {
+		return os.Chmod(dir, perm)
+	}
+	return nil
+}
+
+// filePerm is the mode for files written into a directory with mode
+// dirPerm: the same access without the execute bits, so 0755 gives 0644.
+func filePerm(dirPerm os.FileMode) os.FileMode {
+	return dirPerm &^ 0111
+}
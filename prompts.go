@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// --- Per-Playlist, Per-Language and Per-Model Prompts ---
+
+// Placeholders a playlist prompt template may use.
+const (
+	promptWordsPlaceholder      = "{words}"
+	promptTranscriptPlaceholder = "{transcript}"
+)
+
+// loadPromptTemplates reads a JSON object mapping keys (playlist IDs,
+// language codes or model names, as kind says) to prompt templates. Every template must
+// include the {transcript} placeholder.
+func loadPromptTemplates(path, kind string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s prompts %s: %w", kind, path, err)
+	}
+	var prompts map[string]string
+	if err := json.Unmarshal(data, &prompts); err != nil {
+		return nil, fmt.Errorf("failed to parse %s prompts %s: %w", kind, path, err)
+	}
+	for key, template := range prompts {
+		if !strings.Contains(template, promptTranscriptPlaceholder) {
+			return nil, fmt.Errorf("%s prompts %s: template for %s is missing %s", kind, path, key, promptTranscriptPlaceholder)
+		}
+	}
+	return prompts, nil
+}
+
+// videoPlaylistID returns the playlist a video was listed from, or "" when it
+// came from a channel, -video, -input-file or stored transcripts.
+func videoPlaylistID(video VideoDetails, cfg *AppConfig) string {
+	if video.Source == "" && cfg.FromTranscriptsDir == "" {
+		return cfg.PlaylistID
+	}
+	playlistID, ok := strings.CutPrefix(video.Source, "playlist:")
+	if !ok {
+		return ""
+	}
+	return playlistID
+}
+
+// playlistPromptTemplate returns the configured template for the video's
+// playlist, or "" to use the global prompt.
+func playlistPromptTemplate(video VideoDetails, cfg *AppConfig) string {
+	playlistID := videoPlaylistID(video, cfg)
+	if playlistID == "" {
+		return ""
+	}
+	return cfg.PlaylistPrompts[playlistID]
+}
+
+// languagePromptTemplate returns the -language-prompts template for the
+// transcript language lang, or "" to use the default prompt.
+func languagePromptTemplate(lang string, cfg *AppConfig) string {
+	return cfg.LanguagePrompts[lang]
+}
+
+// modelPromptTemplate returns template when set, else the -model-prompts
+// template for model, so that a playlist or language template still wins
+// and each model otherwise gets the phrasing it was tuned with.
+func modelPromptTemplate(model, template string, cfg *AppConfig) string {
+	if template != "" {
+		return template
+	}
+	return cfg.ModelPrompts[model]
+}
+
+// selectPrompt picks the template for a video (its playlist's, else the one
+// for its transcript language lang, else "" for the default prompt) and the text to
+// summarize, which includes the description under -combine-description and
+// is masked under -redact.
+func selectPrompt(video VideoDetails, transcript, lang string, cfg *AppConfig) (template, promptText string, descriptionIncluded bool) {
+	template = playlistPromptTemplate(video, cfg)
+	if template != "" {
+		log.Printf("  Video %s (%s): Using the prompt template for playlist %s.", video.ID, video.Title, videoPlaylistID(video, cfg))
+	} else if languageTemplate := languagePromptTemplate(lang, cfg); languageTemplate != "" {
+		log.Printf("  Video %s (%s): Using the prompt template for transcript language %s.", video.ID, video.Title, lang)
+		template = languageTemplate
+	}
+	promptText = transcript
+	if cfg.CombineDescription && strings.TrimSpace(video.Description) != "" {
+		promptText = combineDescription(video.Description, transcript)
+		descriptionIncluded = true
+	}
+	if cfg.Redactor != nil {
+		var redactions int
+		promptText, redactions = cfg.Redactor.redact(promptText)
+		if redactions > 0 {
+			log.Printf("  Video %s (%s): Redacted %d PII matches before summarizing.", video.ID, video.Title, redactions)
+		}
+	}
+	return template, promptText, descriptionIncluded
+}
+
+// expandPromptTemplate fills in a playlist, language or model prompt template.
+func expandPromptTemplate(template string, words int, transcript string) string {
+	return strings.NewReplacer(
+		promptWordsPlaceholder, strconv.Itoa(words),
+		promptTranscriptPlaceholder, transcript,
+	).Replace(template)
+}
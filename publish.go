@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- Result Publishing ---
+
+const (
+	envPublishURL         = "SUMMIFY_PUBLISH_URL"
+	envPublishTopic       = "SUMMIFY_PUBLISH_TOPIC"
+	defaultPublishTopic   = "summify.results"
+	defaultPublishBuffer  = 100
+	publishConnectTimeout = 10 * time.Second
+	publishFlushTimeout   = 10 * time.Second
+	publishWriteTimeout   = 10 * time.Second // Per write, so a stalled broker cannot block the queue forever
+	natsDefaultPort       = "4222"
+)
+
+// ResultPublisher sends each finished video's result to a message broker.
+// Close must deliver everything published before it returns.
+type ResultPublisher interface {
+	Publish(topic string, payload []byte) error
+	Close() error
+}
+
+// newResultPublisher connects to the broker named by -publish-url. Only NATS
+// (nats://[user:pass@]host[:port]) is supported; other brokers can be added
+// as further ResultPublisher implementations.
+func newResultPublisher(rawURL string) (ResultPublisher, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -publish-url %q: %w", rawURL, err)
+	}
+	switch u.Scheme {
+	case "nats":
+		return dialNATS(u)
+	default:
+		return nil, fmt.Errorf("unsupported -publish-url scheme %q: only nats:// is supported", u.Scheme)
+	}
+}
+
+// validatePublishTopic rejects topics the broker would split or refuse.
+func validatePublishTopic(topic string) error {
+	if topic == "" || strings.ContainsAny(topic, " \t\r\n") {
+		return fmt.Errorf("invalid -publish-topic %q: must be non-empty and contain no whitespace", topic)
+	}
+	return nil
+}
+
+// resultQueue publishes results in the background through a bounded buffer,
+// so a slow broker holds up the results collector only once the buffer is
+// full. Failed publishes are recorded as warnings. With a rate bucket
+// (-output-rate), messages are paced through it before publishing.
+type resultQueue struct {
+	publisher    ResultPublisher
+	topic        string
+	cfg          *AppConfig // For -empty-placeholder and the summary prefix and suffix
+	rate         *tokenBucket
+	messages     chan []byte
+	done         chan struct{}
+	published    int           // Messages the broker connection accepted
+	throttled    int           // Messages held back by rate
+	throttledFor time.Duration // Total time spent waiting on rate
+}
+
+func newResultQueue(publisher ResultPublisher, cfg *AppConfig, rate *tokenBucket) *resultQueue {
+	q := &resultQueue{publisher: publisher, topic: cfg.PublishTopic, cfg: cfg, rate: rate, messages: make(chan []byte, cfg.PublishBuffer), done: make(chan struct{})}
+	go func() {
+		defer close(q.done)
+		for message := range q.messages {
+			if delay := q.rate.wait(); delay > 0 {
+				if q.throttled == 0 {
+					log.Printf("Throttling published results to the -output-rate limit; %d more are queued.", len(q.messages))
+				}
+				q.throttled++
+				q.throttledFor += delay
+			}
+			if err := q.publisher.Publish(q.topic, message); err != nil {
+				log.Printf("Warning: %s", runWarnings.add(warnOutput, "", "Could not publish result to %s: %v", q.topic, err))
+				continue
+			}
+			q.published++
+		}
+	}()
+	return q
+}
+
+// add queues result as a JSON message in the -append-jsonl record format.
+func (q *resultQueue) add(result ProcessingResult) {
+	message, err := json.Marshal(newResultLogRecord(result, q.cfg))
+	if err != nil {
+		log.Printf("Warning: %s", runWarnings.add(warnOutput, result.VideoDetails.ID, "Could not encode result for publishing: %v", err))
+		return
+	}
+	q.messages <- message
+}
+
+// close publishes everything still queued, then flushes and closes the
+// connection. It returns the number of results published.
+func (q *resultQueue) close() (int, error) {
+	close(q.messages)
+	<-q.done
+	if q.throttled > 0 {
+		log.Printf("The -output-rate limit delayed %d published results by %v in total.", q.throttled, q.throttledFor.Round(time.Millisecond))
+	}
+	return q.published, q.publisher.Close()
+}
+
+// natsPublisher speaks the publishing subset of the NATS text protocol:
+// CONNECT, PUB and PING/PONG.
+type natsPublisher struct {
+	conn   net.Conn
+	mu     sync.Mutex // Guards writer, shared with the PING handler
+	writer *bufio.Writer
+	pongs  chan struct{}
+}
+
+func dialNATS(u *url.URL) (*natsPublisher, error) {
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), natsDefaultPort)
+	}
+	conn, err := net.DialTimeout("tcp", host, publishConnectTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS at %s: %w", host, err)
+	}
+	conn.SetDeadline(time.Now().Add(publishConnectTimeout))
+	reader := bufio.NewReader(conn)
+	if line, err := reader.ReadString('\n'); err != nil || !strings.HasPrefix(line, "INFO ") {
+		conn.Close()
+		return nil, fmt.Errorf("NATS server at %s did not send INFO (got %q): %v", host, strings.TrimSpace(line), err)
+	}
+
+	options := map[string]any{"verbose": false, "pedantic": false, "name": "summify", "lang": "go", "version": "1"}
+	if u.User != nil {
+		if password, ok := u.User.Password(); ok {
+			options["user"], options["pass"] = u.User.Username(), password
+		} else {
+			options["auth_token"] = u.User.Username()
+		}
+	}
+	connect, _ := json.Marshal(options)
+	p := &natsPublisher{conn: conn, writer: bufio.NewWriter(conn), pongs: make(chan struct{}, 1)}
+	fmt.Fprintf(p.writer, "CONNECT %s\r\nPING\r\n", connect)
+	if err := p.writer.Flush(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to send CONNECT to NATS at %s: %w", host, err)
+	}
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("NATS server at %s closed the connection during CONNECT: %w", host, err)
+		}
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "-ERR") {
+			conn.Close()
+			return nil, fmt.Errorf("NATS server at %s rejected the connection: %s", host, line)
+		}
+		if line == "PONG" {
+			break
+		}
+	}
+	conn.SetDeadline(time.Time{})
+	go p.readLoop(reader)
+	return p, nil
+}
+
+// readLoop answers the server's keep-alive PINGs, passes PONGs on to Close
+// and records protocol errors, until the connection is closed.
+func (p *natsPublisher) readLoop(reader *bufio.Reader) {
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			close(p.pongs)
+			return
+		}
+		switch line = strings.TrimSpace(line); {
+		case line == "PING":
+			p.mu.Lock()
+			p.writer.WriteString("PONG\r\n")
+			p.flush()
+			p.mu.Unlock()
+		case line == "PONG":
+			select {
+			case p.pongs <- struct{}{}:
+			default:
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			log.Printf("Warning: %s", runWarnings.add(warnOutput, "", "NATS server error: %s", line))
+		}
+	}
+}
+
+// Publish writes one message and flushes it to the connection, so that a nil
+// error means the message left Summify rather than sitting in a buffer.
+func (p *natsPublisher) Publish(topic string, payload []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Fprintf(p.writer, "PUB %s %d\r\n", topic, len(payload))
+	p.writer.Write(payload)
+	p.writer.WriteString("\r\n")
+	return p.flush()
+}
+
+// flush writes out the buffer within publishWriteTimeout. The caller must
+// hold p.mu.
+func (p *natsPublisher) flush() error {
+	p.conn.SetWriteDeadline(time.Now().Add(publishWriteTimeout))
+	return p.writer.Flush()
+}
+
+// Close flushes pending messages and waits for the server's PONG, which it
+// sends only after processing everything before the PING.
+func (p *natsPublisher) Close() error {
+	defer p.conn.Close()
+	p.mu.Lock()
+	p.writer.WriteString("PING\r\n")
+	err := p.flush()
+	p.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to flush NATS messages: %w", err)
+	}
+	select {
+	case _, ok := <-p.pongs:
+		if !ok {
+			return fmt.Errorf("NATS connection closed before all messages were confirmed")
+		}
+		return nil
+	case <-time.After(publishFlushTimeout):
+		return fmt.Errorf("timed out after %v waiting for NATS to confirm published messages", publishFlushTimeout)
+	}
+}
+
+// redactPublishURL hides the credentials or token in a -publish-url for
+// logging.
+func redactPublishURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "<unparseable URL>"
+	}
+	if u.User != nil {
+		u.User = url.User("xxxxx")
+	}
+	return u.String()
+}
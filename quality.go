@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// --- Transcript Quality ---
+
+// Values accepted by -low-quality.
+const (
+	lowQualitySkip = "skip"
+	lowQualityFlag = "flag"
+)
+
+const (
+	// goodPunctuationDensity is about one sentence or clause mark every eight
+	// words, typical of human-written captions; denser scores no higher.
+	goodPunctuationDensity = 0.125
+	// goodUniqueWordRatio is the share of distinct words in a window of
+	// qualityWindowWords that ordinary speech reaches.
+	goodUniqueWordRatio = 0.55
+	qualityWindowWords  = 100
+	// repeatedBigramLookback is how many preceding word pairs a pair is
+	// compared with; rolling captions repeat pairs within a few words.
+	repeatedBigramLookback = 12
+)
+
+// qualityScores rates how well a transcript is likely to summarize.
+// Each measure and the overall Score range from 0 (poor) to 1 (good).
+type qualityScores struct {
+	Punctuation     float64 // Sentence and clause marks per word, relative to goodPunctuationDensity
+	UniqueWords     float64 // Distinct-word ratio per window, relative to goodUniqueWordRatio
+	RepeatedBigrams float64 // Share of word pairs that repeat one of the few pairs before them
+	Score           float64
+}
+
+func (q qualityScores) String() string {
+	return fmt.Sprintf("%.2f (punctuation %.2f, unique words %.2f, repeated bigrams %.0f%%)", q.Score, q.Punctuation, q.UniqueWords, 100*q.RepeatedBigrams)
+}
+
+// measureTranscriptQuality scores transcript for -min-quality. Auto-captions
+// with hardly any punctuation, a small vocabulary or rolling-caption
+// repetition score low. An empty transcript scores 0.
+func measureTranscriptQuality(transcript string) qualityScores {
+	var words []string
+	marks := 0
+	for _, field := range strings.Fields(transcript) {
+		marks += strings.Count(field, ".") + strings.Count(field, ",") + strings.Count(field, "?") +
+			strings.Count(field, "!") + strings.Count(field, ";") + strings.Count(field, ":")
+		if word := strings.ToLower(strings.TrimFunc(field, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })); word != "" {
+			words = append(words, word)
+		}
+	}
+	if len(words) == 0 {
+		return qualityScores{}
+	}
+
+	var q qualityScores
+	q.Punctuation = min(1, float64(marks)/float64(len(words))/goodPunctuationDensity)
+
+	var ratios float64
+	windows := 0
+	for start := 0; start < len(words); start += qualityWindowWords {
+		window := words[start:min(start+qualityWindowWords, len(words))]
+		if len(window) < qualityWindowWords/2 && windows > 0 {
+			break // A short tail would skew the average upwards
+		}
+		distinct := make(map[string]bool, len(window))
+		for _, word := range window {
+			distinct[word] = true
+		}
+		ratios += float64(len(distinct)) / float64(len(window))
+		windows++
+	}
+	q.UniqueWords = min(1, ratios/float64(windows)/goodUniqueWordRatio)
+
+	if len(words) > 1 {
+		repeated := 0
+		for i := 1; i < len(words); i++ {
+			for j := max(1, i-repeatedBigramLookback); j < i; j++ {
+				if words[j-1] == words[i-1] && words[j] == words[i] {
+					repeated++
+					break
+				}
+			}
+		}
+		q.RepeatedBigrams = float64(repeated) / float64(len(words)-1)
+	}
+
+	q.Score = (q.Punctuation + q.UniqueWords + (1 - min(1, 2*q.RepeatedBigrams))) / 3
+	return q
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// --- PII Redaction ---
+
+// redactionRule masks every match of pattern with replacement. When valid
+// is set, only matches it accepts are masked.
+type redactionRule struct {
+	pattern     *regexp.Regexp
+	replacement string
+	valid       func(match string) bool
+}
+
+// defaultRedactionRules cover the common PII -redact masks. Card numbers come
+// before phone numbers so a card is not half-masked as a phone number; only
+// numbers that pass the Luhn check count as cards, so long numbers such as
+// order IDs or figures read out in a video are kept.
+var defaultRedactionRules = []redactionRule{
+	{regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`), "[REDACTED EMAIL]", nil},
+	{regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`), "[REDACTED NUMBER]", luhnValid},
+	{regexp.MustCompile(`(?:\+\d{1,3}[\s.-]?)?\(?\b\d{3}\)?[\s.-]?\d{3}[\s.-]?\d{4}\b`), "[REDACTED PHONE]", nil},
+}
+
+// luhnValid reports whether the digits in number pass the Luhn checksum
+// that card numbers carry. Spaces and dashes are ignored.
+func luhnValid(number string) bool {
+	sum, digits := 0, 0
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if digits%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		digits++
+	}
+	return digits > 0 && sum%10 == 0
+}
+
+// redactor masks PII in text before it is sent to the LLM.
+type redactor struct {
+	rules []redactionRule
+}
+
+// newRedactor returns a redactor with the default rules plus one rule per
+// extra regular expression from -redact-pattern.
+func newRedactor(extraPatterns []string) (*redactor, error) {
+	rules := append([]redactionRule(nil), defaultRedactionRules...)
+	for _, pattern := range extraPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -redact-pattern %q: %w", pattern, err)
+		}
+		rules = append(rules, redactionRule{re, "[REDACTED]", nil})
+	}
+	return &redactor{rules: rules}, nil
+}
+
+// redact returns text with every rule applied and the number of matches
+// masked.
+func (r *redactor) redact(text string) (string, int) {
+	count := 0
+	for _, rule := range r.rules {
+		text = rule.pattern.ReplaceAllStringFunc(text, func(match string) string {
+			if rule.valid != nil && !rule.valid(match) {
+				return match
+			}
+			count++
+			return rule.replacement
+		})
+	}
+	return text, count
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+)
+
+// --- Append-Only Result Log ---
+
+// resultLogRecord is one line of the -append-jsonl file.
+type resultLogRecord struct {
+	VideoID        string    `json:"video_id"`
+	Title          string    `json:"title"`
+	GeneratedTitle string    `json:"generated_title,omitempty"`
+	Summary        string    `json:"summary,omitempty"`
+	RawSummary     string    `json:"raw_summary,omitempty"` // Summary without -summary-prefix/-summary-suffix, when they are set
+	Placeholder    bool      `json:"placeholder,omitempty"` // Summary is the -empty-placeholder text
+	TranscriptPath string    `json:"transcript_path,omitempty"`
+	Truncated      bool      `json:"truncated,omitempty"`
+	Structured     *Extract  `json:"structured,omitempty"` // -structured summary
+	ContextFrom    string    `json:"series_context_from,omitempty"`
+	Unsupported    []string  `json:"unsupported_claims,omitempty"` // From -verify; empty when every claim was supported
+	Verified       bool      `json:"verified,omitempty"`
+	Language       string    `json:"summary_language,omitempty"`
+	Track          string    `json:"subtitle_track,omitempty"`
+	Error          string    `json:"error,omitempty"`
+	CompletedAt    time.Time `json:"completed_at"`
+}
+
+// done reports whether the record finished the work a run of this mode is
+// for, so a restarted run can skip the video.
+func (r resultLogRecord) done(transcriptsOnly bool) bool {
+	if r.Error != "" {
+		return false
+	}
+	if transcriptsOnly {
+		return r.TranscriptPath != ""
+	}
+	return r.Summary != "" && !r.Placeholder
+}
+
+// resultLog appends one JSON line per finished video. Each line is written
+// with a single write call, so a crash leaves at most a partial last line,
+// which readResultLog skips and openResultLog cuts off.
+type resultLog struct {
+	file  *os.File
+	fsync bool
+	cfg   *AppConfig // For -empty-placeholder and the summary prefix and suffix
+}
+
+// openResultLog opens cfg.AppendJSONL for appending, creating it if needed.
+func openResultLog(cfg *AppConfig) (*resultLog, error) {
+	path := cfg.AppendJSONL
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, filePerm(cfg.TempPerms))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open result log %s: %w", path, err)
+	}
+	removed, err := trimPartialLastLine(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to repair result log %s: %w", path, err)
+	}
+	if removed > 0 {
+		log.Printf("Warning: %s", runWarnings.add(warnOutput, "", "Removed a partial last line (%d bytes) from %s, left by an interrupted run.", removed, path))
+	}
+	return &resultLog{file: file, fsync: cfg.AppendJSONLFsync, cfg: cfg}, nil
+}
+
+// trimPartialLastLine truncates file after its last newline, so the next
+// record is not appended onto a line a crash left unfinished. It returns the
+// number of bytes removed.
+func trimPartialLastLine(file *os.File) (int64, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	size := info.Size()
+	chunk := make([]byte, 4096)
+	for end := size; end > 0; {
+		start := max(0, end-int64(len(chunk)))
+		n, err := file.ReadAt(chunk[:end-start], start)
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+		if i := bytes.LastIndexByte(chunk[:n], '\n'); i >= 0 {
+			keep := start + int64(i) + 1
+			if keep == size {
+				return 0, nil
+			}
+			return size - keep, file.Truncate(keep)
+		}
+		end = start
+	}
+	if size == 0 {
+		return 0, nil
+	}
+	return size, file.Truncate(0)
+}
+
+// newResultLogRecord describes a finished video, with the summary wrapped in
+// -summary-prefix and -summary-suffix and the raw summary kept alongside. An
+// empty summary is replaced by the -empty-placeholder text, if set.
+func newResultLogRecord(result ProcessingResult, cfg *AppConfig) resultLogRecord {
+	wrapped := wrapResult(result, cfg)
+	record := resultLogRecord{
+		VideoID:        result.VideoDetails.ID,
+		Title:          result.VideoDetails.Title,
+		GeneratedTitle: result.GeneratedTitle,
+		Summary:        wrapped.Summary,
+		TranscriptPath: result.TranscriptPath,
+		Truncated:      result.Truncated,
+		Structured:     wrapped.Structured,
+		ContextFrom:    result.ContextFrom,
+		Unsupported:    result.UnsupportedClaims,
+		Verified:       result.UnsupportedClaims != nil,
+		Track:          result.SubtitleTrack,
+		CompletedAt:    time.Now().UTC(),
+	}
+	if result.Summary != "" {
+		record.Language = result.SummaryLanguage
+	}
+	if wrapped.Summary != result.Summary {
+		record.RawSummary = result.Summary
+	}
+	if result.Err != nil {
+		record.Error = result.Err.Error()
+	}
+	if record.Summary == "" && cfg.EmptyPlaceholder != "" {
+		record.Summary, record.Placeholder = cfg.EmptyPlaceholder, true
+	}
+	return record
+}
+
+func (l *resultLog) append(result ProcessingResult) error {
+	line, err := json.Marshal(newResultLogRecord(result, l.cfg))
+	if err != nil {
+		return err
+	}
+	if _, err := l.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to append to result log %s: %w", l.file.Name(), err)
+	}
+	if l.fsync {
+		return l.file.Sync()
+	}
+	return nil
+}
+
+func (l *resultLog) Close() error {
+	return l.file.Close()
+}
+
+// readResultLog returns the last record of each video in the -append-jsonl
+// file. A missing file is not an error; lines that do not parse (such as a
+// partial line from a crash) are skipped with a warning.
+func readResultLog(path string) (map[string]resultLogRecord, error) {
+	records := make(map[string]resultLogRecord)
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return records, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read result log %s: %w", path, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var record resultLogRecord
+		if json.Unmarshal(scanner.Bytes(), &record) != nil || record.VideoID == "" {
+			log.Printf("Warning: %s", runWarnings.add(warnOutput, "", "Skipping unreadable line %d of %s.", line, path))
+			continue
+		}
+		records[record.VideoID] = record
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read result log %s: %w", path, err)
+	}
+	return records, nil
+}
+
+// skipLoggedVideos drops the videos whose last -append-jsonl record is done.
+func skipLoggedVideos(videos []VideoDetails, records map[string]resultLogRecord, transcriptsOnly bool) ([]VideoDetails, int) {
+	var remaining []VideoDetails
+	for _, video := range videos {
+		if record, ok := records[video.ID]; ok && record.done(transcriptsOnly) {
+			continue
+		}
+		remaining = append(remaining, video)
+	}
+	return remaining, len(videos) - len(remaining)
+}
+
+// resultReorderer releases results in the order of the video list for
+// -ordered-stream: a result is held until every earlier video has finished,
+// so one slow video keeps all later finished results in memory.
+type resultReorderer struct {
+	position map[string]int
+	pending  map[int]ProcessingResult
+	next     int
+}
+
+func newResultReorderer(videos []VideoDetails) *resultReorderer {
+	position := make(map[string]int, len(videos))
+	for i, video := range videos {
+		position[video.ID] = i
+	}
+	return &resultReorderer{position: position, pending: make(map[int]ProcessingResult)}
+}
+
+// add buffers result and returns the results that are now next in order.
+func (r *resultReorderer) add(result ProcessingResult) []ProcessingResult {
+	r.pending[r.position[result.VideoDetails.ID]] = result
+	var ready []ProcessingResult
+	for {
+		next, ok := r.pending[r.next]
+		if !ok {
+			return ready
+		}
+		delete(r.pending, r.next)
+		ready = append(ready, next)
+		r.next++
+	}
+}
+
+// flush returns the results still held, in order, skipping videos that never
+// produced one (e.g. because the run was stopped).
+func (r *resultReorderer) flush() []ProcessingResult {
+	var rest []ProcessingResult
+	for i := r.next; len(r.pending) > 0; i++ {
+		if result, ok := r.pending[i]; ok {
+			delete(r.pending, i)
+			rest = append(rest, result)
+		}
+	}
+	return rest
+}
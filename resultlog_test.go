@@ -0,0 +1,83 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResultLogRecoversFromPartialLastLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+		want     []string // Video IDs readable after one more append
+	}{
+		{"partial last line", `{"video_id":"a","summary":"one"}` + "\n" + `{"video_id":"b","summ`, []string{"a", "c"}},
+		{"only a partial line", `{"video_id":"b","summ`, []string{"c"}},
+		{"complete lines", `{"video_id":"a","summary":"one"}` + "\n", []string{"a", "c"}},
+		{"empty file", "", []string{"c"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "results.jsonl")
+			if err := os.WriteFile(path, []byte(tt.existing), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := readResultLog(path); err != nil {
+				t.Fatalf("readResultLog: %v", err)
+			}
+			resultLog, err := openResultLog(&AppConfig{AppendJSONL: path, TempPerms: 0755})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := resultLog.append(ProcessingResult{VideoDetails: VideoDetails{ID: "c"}, Summary: "three"}); err != nil {
+				t.Fatal(err)
+			}
+			if err := resultLog.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+			if len(lines) != len(tt.want) {
+				t.Fatalf("result log has %d lines, want %d:\n%s", len(lines), len(tt.want), data)
+			}
+			records, err := readResultLog(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, id := range tt.want {
+				if _, ok := records[id]; !ok {
+					t.Errorf("record for %q missing after append:\n%s", id, data)
+				}
+			}
+		})
+	}
+}
+
+func TestNewResultLogRecordWrapsSummary(t *testing.T) {
+	suffix, err := parseSummaryTemplate("summary-suffix", "Watch: {{.URL}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &AppConfig{SummarySuffix: suffix}
+	result := ProcessingResult{VideoDetails: VideoDetails{ID: "abc"}, Summary: "A summary.", Structured: &Extract{OneLiner: "A summary."}}
+
+	record := newResultLogRecord(result, cfg)
+	if want := "A summary.\n\nWatch: https://www.youtube.com/watch?v=abc"; record.Summary != want {
+		t.Errorf("Summary = %q, want %q", record.Summary, want)
+	}
+	if record.RawSummary != "A summary." {
+		t.Errorf("RawSummary = %q, want %q", record.RawSummary, "A summary.")
+	}
+	if record.Structured.Suffix != "Watch: https://www.youtube.com/watch?v=abc" || result.Structured.Suffix != "" {
+		t.Errorf("Structured.Suffix = %q (result's %q), want it set on the record only", record.Structured.Suffix, result.Structured.Suffix)
+	}
+	if result.Summary != "A summary." {
+		t.Errorf("result.Summary = %q, want it left raw", result.Summary)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// --- Generated Titles ---
+
+const (
+	retitlePromptFormat    = "This is a summary of a YouTube video titled %q. Write one concise, descriptive title for the video (at most 12 words) that says what it actually covers, without clickbait, emoji or quotation marks. Reply with the title only.\n\n%s"
+	maxGeneratedTitleRunes = 150
+)
+
+// generateTitle asks Gemini for a descriptive title based on the video's
+// summary, for -retitle. The original title is sent along for context only.
+func generateTitle(ctx context.Context, gemini *rotatingGeminiModel, video VideoDetails, summary string, cfg *AppConfig) (string, error) {
+	response, _, err := generateWithGemini(ctx, gemini, fmt.Sprintf(retitlePromptFormat, video.Title, summary), cfg)
+	if err != nil {
+		return "", err
+	}
+	title := cleanGeneratedTitle(response)
+	if title == "" {
+		return "", fmt.Errorf("the generated title was empty")
+	}
+	return title, nil
+}
+
+// cleanGeneratedTitle keeps the first non-empty line of the model's reply
+// and strips a "Title:" label, Markdown emphasis and surrounding quotes.
+func cleanGeneratedTitle(response string) string {
+	title := ""
+	for _, line := range strings.Split(response, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			title = line
+			break
+		}
+	}
+	if label, rest, found := strings.Cut(title, ":"); found && strings.EqualFold(strings.TrimSpace(label), "title") {
+		title = rest
+	}
+	title = strings.Trim(strings.TrimSpace(title), "#*_\"'“”‘’` ")
+	if runes := []rune(title); len(runes) > maxGeneratedTitleRunes {
+		title = strings.TrimSpace(string(runes[:maxGeneratedTitleRunes]))
+	}
+	return title
+}
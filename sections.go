@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// --- Sectioned Summaries ---
+
+const defaultSectionHeaders = "Overview,Key Points,Takeaways"
+
+// Section is one headed part of a -sections summary.
+type Section struct {
+	Header string // Empty for text the model wrote before the first header
+	Text   string
+}
+
+// sectionHeaderPattern matches a line that is only a header, in the forms
+// models tend to use: "## Overview", "**Overview**", "Overview:".
+var sectionHeaderPattern = regexp.MustCompile(`^\s*(?:#{1,6}\s*)?(?:\*\*|__)?\s*([^*_:#]+?)\s*:?\s*(?:\*\*|__)?\s*:?\s*$`)
+
+// sectionsPrompt asks for the summary under exactly the given headers.
+func sectionsPrompt(headers []string) string {
+	lines := make([]string, len(headers))
+	for i, header := range headers {
+		lines[i] = "## " + header
+	}
+	return fmt.Sprintf("\n\nStructure the summary under exactly these Markdown headers, in this order, each on its own line, with no other headers:\n%s", strings.Join(lines, "\n"))
+}
+
+// parseSections splits summary at the expected headers, matched case-
+// insensitively. Text before the first header, such as a sentence of
+// preamble, is kept as a leading section without a header. ok is false
+// unless every header appears, in which case the summary should be used as
+// it is.
+func parseSections(summary string, headers []string) (sections []Section, ok bool) {
+	index := make(map[string]int, len(headers))
+	for i, header := range headers {
+		index[strings.ToLower(header)] = i
+	}
+	found := make([]bool, len(headers))
+	current := &Section{}
+	var text []string
+	finish := func() {
+		current.Text = strings.TrimSpace(strings.Join(text, "\n"))
+		if current.Header != "" || current.Text != "" {
+			sections = append(sections, *current)
+		}
+	}
+	for _, line := range strings.Split(summary, "\n") {
+		if match := sectionHeaderPattern.FindStringSubmatch(line); match != nil {
+			if i, known := index[strings.ToLower(match[1])]; known && !found[i] {
+				finish()
+				found[i] = true
+				current, text = &Section{Header: headers[i]}, nil
+				continue
+			}
+		}
+		text = append(text, line)
+	}
+	finish()
+	for _, f := range found {
+		if !f {
+			return nil, false
+		}
+	}
+	return sections, true
+}
+
+// renderSections writes the sections as Markdown under headers of the given
+// level ("###" nests them inside a "## Summary" heading). A leading section
+// without a header is written as plain text.
+func renderSections(sections []Section, level string) string {
+	parts := make([]string, len(sections))
+	for i, section := range sections {
+		if section.Header == "" {
+			parts[i] = section.Text
+			continue
+		}
+		parts[i] = level + " " + section.Header + "\n\n" + section.Text
+	}
+	return strings.Join(parts, "\n\n")
+}
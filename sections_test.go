@@ -0,0 +1,42 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSections(t *testing.T) {
+	headers := []string{"Overview", "Takeaways"}
+	tests := []struct {
+		name    string
+		summary string
+		want    []Section
+		wantOK  bool
+	}{
+		{
+			name:    "headers only",
+			summary: "## Overview\nWhat it covers.\n\n**Takeaways**\nWhat to remember.",
+			want:    []Section{{"Overview", "What it covers."}, {"Takeaways", "What to remember."}},
+			wantOK:  true,
+		},
+		{
+			name:    "preamble before the first header",
+			summary: "Here is the summary you asked for.\n\n## Overview\nWhat it covers.\n## Takeaways\nWhat to remember.",
+			want:    []Section{{"", "Here is the summary you asked for."}, {"Overview", "What it covers."}, {"Takeaways", "What to remember."}},
+			wantOK:  true,
+		},
+		{
+			name:    "missing header",
+			summary: "## Overview\nWhat it covers.",
+			wantOK:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseSections(tt.summary, headers)
+			if ok != tt.wantOK || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseSections() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"strings"
+	"sync"
+)
+
+// --- Series Context ---
+
+const seriesPromptFormat = "This video is part of a series. The previous video in the series was summarized as follows. Treat it as already covered: build on it and note continuity where relevant, without re-explaining it. Do not summarize it again.\n[Previously covered]\n%s\n[End of previously covered]\n\n"
+
+// seriesChain hands each video the summary of the video before it from the
+// same playlist or channel, for -series-context. Each playlist or channel is
+// its own chain, so context never crosses a playlist boundary; videos from
+// -video, -input-file and other unordered sources are not chained. Videos
+// are processed one at a time, so a video's predecessor has always finished
+// by the time it starts.
+type seriesChain struct {
+	mu        sync.Mutex
+	previous  map[string]string // Video ID -> ID of the video before it in its source
+	summaries map[string]string // Video ID -> its summary, once known
+}
+
+// newSeriesChain links videos in processing order (after -order) within
+// each playlist or channel source.
+func newSeriesChain(videos []VideoDetails) *seriesChain {
+	chain := &seriesChain{previous: make(map[string]string), summaries: make(map[string]string)}
+	last := make(map[string]string)
+	for _, video := range videos {
+		if !isSeriesSource(video.Source) {
+			continue
+		}
+		if id, ok := last[video.Source]; ok {
+			chain.previous[video.ID] = id
+		}
+		last[video.Source] = video.ID
+	}
+	return chain
+}
+
+// isSeriesSource reports whether source lists videos in a meaningful order:
+// a -playlists playlist, a -channel's uploads or PLAYLIST_ID ("").
+func isSeriesSource(source string) bool {
+	return source == "" || strings.HasPrefix(source, "playlist:") || strings.HasPrefix(source, "channel:")
+}
+
+// seed takes the summaries of videos already done in the -append-jsonl log,
+// so a resumed run gives later videos the same context as a full run.
+func (c *seriesChain) seed(records map[string]resultLogRecord) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for id, record := range records {
+		summary := record.Summary
+		if record.RawSummary != "" {
+			summary = record.RawSummary
+		}
+		if record.Error == "" && summary != "" && summary != emptyTranscriptSummary && !record.Placeholder {
+			c.summaries[id] = summary
+		}
+	}
+}
+
+// context returns the summary of the nearest earlier video in the same
+// source that has one, and that video's ID. Both are empty for the first
+// video of a source.
+func (c *seriesChain) context(videoID string) (fromID, summary string) {
+	if c == nil {
+		return "", ""
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for id, ok := c.previous[videoID]; ok; id, ok = c.previous[id] {
+		if summary := c.summaries[id]; summary != "" {
+			return id, summary
+		}
+	}
+	return "", ""
+}
+
+// record stores a finished video's summary for the videos after it. The
+// empty-transcript placeholder is not a summary and is skipped.
+func (c *seriesChain) record(videoID, summary string) {
+	if c == nil || summary == "" || summary == emptyTranscriptSummary {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.summaries[videoID] = summary
+}
@@ -0,0 +1,42 @@
+package main
+
+import "testing"
+
+func TestSeriesChainSources(t *testing.T) {
+	videos := []VideoDetails{
+		{ID: "p1", Source: "playlist:PL1"},
+		{ID: "v1", Source: "video"},
+		{ID: "p2", Source: "playlist:PL1"},
+		{ID: "v2", Source: "video"},
+		{ID: "c1", Source: "channel:@name"},
+		{ID: "c2", Source: "channel:@name"},
+	}
+	chain := newSeriesChain(videos)
+	for _, video := range videos {
+		chain.record(video.ID, "summary of "+video.ID)
+	}
+	tests := []struct {
+		videoID  string
+		wantFrom string
+	}{
+		{"p1", ""},
+		{"p2", "p1"},
+		{"v1", ""},
+		{"v2", ""},
+		{"c2", "c1"},
+	}
+	for _, tt := range tests {
+		if from, _ := chain.context(tt.videoID); from != tt.wantFrom {
+			t.Errorf("context(%q) came from %q, want %q", tt.videoID, from, tt.wantFrom)
+		}
+	}
+}
+
+func TestSeriesChainSkipsEmptyTranscriptPlaceholder(t *testing.T) {
+	chain := newSeriesChain([]VideoDetails{{ID: "a"}, {ID: "b"}, {ID: "c"}})
+	chain.record("a", "the real summary")
+	chain.record("b", emptyTranscriptSummary)
+	if from, summary := chain.context("c"); from != "a" || summary != "the real summary" {
+		t.Errorf("context(\"c\") = %q, %q; want \"a\", \"the real summary\"", from, summary)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// --- Near-Duplicate Detection ---
+
+// similarityStopWords are dropped before comparing summaries so that shared
+// filler words do not make unrelated summaries look alike.
+var similarityStopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "by": true,
+	"for": true, "from": true, "has": true, "in": true, "is": true, "it": true, "its": true, "of": true,
+	"on": true, "or": true, "that": true, "the": true, "this": true, "to": true, "video": true,
+	"was": true, "with": true,
+}
+
+// summaryTokenSet returns the distinct lowercase words of text, minus stop words.
+func summaryTokenSet(text string) map[string]struct{} {
+	tokens := make(map[string]struct{})
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		if !similarityStopWords[word] {
+			tokens[word] = struct{}{}
+		}
+	}
+	return tokens
+}
+
+// tokenSetCosine returns the cosine similarity of two token sets treated as
+// binary vectors: |A∩B| / sqrt(|A|·|B|).
+func tokenSetCosine(a, b map[string]struct{}) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for token := range a {
+		if _, ok := b[token]; ok {
+			shared++
+		}
+	}
+	return float64(shared) / math.Sqrt(float64(len(a)*len(b)))
+}
+
+// findNearDuplicateClusters groups results whose summaries are at least
+// threshold similar, linking transitively. Only clusters with two or more
+// videos are returned, each in the order the results were given.
+func findNearDuplicateClusters(results []ProcessingResult, threshold float64) [][]ProcessingResult {
+	tokenSets := make([]map[string]struct{}, len(results))
+	for i, result := range results {
+		tokenSets[i] = summaryTokenSet(result.Summary)
+	}
+
+	parent := make([]int, len(results))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	for i := range results {
+		for j := i + 1; j < len(results); j++ {
+			if tokenSetCosine(tokenSets[i], tokenSets[j]) >= threshold {
+				parent[find(j)] = find(i)
+			}
+		}
+	}
+
+	members := make(map[int][]ProcessingResult)
+	var roots []int
+	for i, result := range results {
+		root := find(i)
+		if _, seen := members[root]; !seen {
+			roots = append(roots, root)
+		}
+		members[root] = append(members[root], result)
+	}
+	var clusters [][]ProcessingResult
+	for _, root := range roots {
+		if len(members[root]) > 1 {
+			clusters = append(clusters, members[root])
+		}
+	}
+	return clusters
+}
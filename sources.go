@@ -0,0 +1,434 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/api/youtube/v3"
+)
+
+// --- Video Sources ---
+
+// maxVideosPerRequest is the largest number of IDs Videos.List accepts.
+const maxVideosPerRequest = 50
+
+// hasExplicitSources reports whether any of -playlists, -channel, -video or
+// -input-file was given, in which case PLAYLIST_ID is not used.
+func hasExplicitSources(cfg *AppConfig) bool {
+	return len(cfg.Playlists) > 0 || len(cfg.Channels) > 0 || len(cfg.VideoIDs) > 0 || cfg.InputFile != ""
+}
+
+// collectVideos resolves every configured source and merges the results in
+// source order: playlists, then channels, then -video IDs, then the input
+// file. A video listed by several sources is kept once, tagged with the first
+// source that listed it. Playlist items that cannot be processed are counted
+// in skipped.
+func collectVideos(ctx context.Context, yt *rotatingYouTubeService, cfg *AppConfig, titles *playlistTitleCache, skipped skippedPlaylistItems) ([]VideoDetails, error) {
+	if !hasExplicitSources(cfg) {
+		titles.lookup(ctx, yt, cfg.PlaylistID)
+		videos, err := getPlaylistVideos(ctx, yt, cfg.PlaylistID, cfg.UntilVideoID, cfg.PositionRange, 0, skipped)
+		if err != nil {
+			return nil, err
+		}
+		return applyAddedSince(videos, cfg), nil
+	}
+
+	var lists [][]VideoDetails
+	for _, playlistID := range cfg.Playlists {
+		videos, err := getPlaylistVideos(ctx, yt, playlistID, cfg.UntilVideoID, cfg.PositionRange, 0, skipped)
+		if err != nil {
+			return nil, err
+		}
+		videos = tagSource(videos, "playlist:"+playlistID, "playlist "+titles.lookup(ctx, yt, playlistID))
+		lists = append(lists, applyAddedSince(videos, cfg))
+	}
+	for _, channel := range cfg.Channels {
+		uploadsID, err := getChannelUploadsPlaylist(ctx, yt, channel)
+		if err != nil {
+			return nil, err
+		}
+		videos, err := getPlaylistVideos(ctx, yt, uploadsID, cfg.UntilVideoID, cfg.PositionRange, cfg.ChannelLimit, skipped)
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, applyAddedSince(tagSource(videos, "channel:"+channel, "channel "+channel), cfg))
+	}
+	if len(cfg.VideoIDs) > 0 {
+		videos, err := getVideosByID(ctx, yt, cfg.VideoIDs)
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, tagSource(videos, "video", "-video"))
+	}
+	if cfg.InputFile != "" {
+		ids, wordCounts, err := readVideoIDsFile(cfg.InputFile)
+		if err != nil {
+			return nil, err
+		}
+		videos, err := getVideosByID(ctx, yt, ids)
+		if err != nil {
+			return nil, err
+		}
+		for i := range videos {
+			videos[i].WordCount = wordCounts[videos[i].ID]
+		}
+		lists = append(lists, tagSource(videos, "file:"+cfg.InputFile, "input file "+cfg.InputFile))
+	}
+
+	merged := mergeVideoLists(lists...)
+	total := 0
+	for _, list := range lists {
+		total += len(list)
+	}
+	if total != len(merged) {
+		log.Printf("Merged %d videos from %d sources into %d unique videos.", total, len(lists), len(merged))
+	}
+	return merged, nil
+}
+
+// applyAddedSince applies -added-since to a playlist's videos, logging how
+// many were kept. Videos from -video and -input-file have no playlist
+// addition time and are never passed through it.
+func applyAddedSince(videos []VideoDetails, cfg *AppConfig) []VideoDetails {
+	if cfg.AddedSince.IsZero() {
+		return videos
+	}
+	kept := filterAddedSince(videos, cfg.AddedSince)
+	log.Printf("Kept %d of %d videos added to the playlist since %s.", len(kept), len(videos), cfg.AddedSince.Format(time.RFC3339))
+	return kept
+}
+
+// filterByTitle keeps the videos whose title matches match (when set) and
+// does not match exclude (when set).
+func filterByTitle(videos []VideoDetails, match, exclude *regexp.Regexp) []VideoDetails {
+	var kept []VideoDetails
+	for _, video := range videos {
+		if match != nil && !match.MatchString(video.Title) {
+			continue
+		}
+		if exclude != nil && exclude.MatchString(video.Title) {
+			continue
+		}
+		kept = append(kept, video)
+	}
+	return kept
+}
+
+// positionRange is the -position-start/-position-end window of playlist
+// positions, counted from 1 as YouTube shows them; 0 leaves that side open.
+type positionRange struct {
+	start, end int64
+}
+
+func (r positionRange) set() bool {
+	return r.start > 0 || r.end > 0
+}
+
+// includes reports whether the zero-based snippet.position is in the window.
+func (r positionRange) includes(position int64) bool {
+	return position+1 >= r.start && !r.past(position)
+}
+
+// past reports whether the zero-based snippet.position is after the window,
+// so no later playlist item can be in it.
+func (r positionRange) past(position int64) bool {
+	return r.end > 0 && position+1 > r.end
+}
+
+func (r positionRange) String() string {
+	start, end := "1", "end"
+	if r.start > 0 {
+		start = strconv.FormatInt(r.start, 10)
+	}
+	if r.end > 0 {
+		end = strconv.FormatInt(r.end, 10)
+	}
+	return start + "-" + end
+}
+
+func tagSource(videos []VideoDetails, source, label string) []VideoDetails {
+	for i := range videos {
+		videos[i].Source = source
+		videos[i].SourceLabel = label
+	}
+	return videos
+}
+
+// playlistTitleCache looks playlist titles up once per playlist so that
+// reports can show them instead of opaque IDs.
+type playlistTitleCache struct {
+	titles map[string]string
+}
+
+func newPlaylistTitleCache() *playlistTitleCache {
+	return &playlistTitleCache{titles: make(map[string]string)}
+}
+
+// lookup returns the playlist's title, fetching it on first use. When the
+// title cannot be fetched the ID is returned and remembered instead.
+func (c *playlistTitleCache) lookup(ctx context.Context, yt *rotatingYouTubeService, playlistID string) string {
+	if title, ok := c.titles[playlistID]; ok {
+		return title
+	}
+	title, err := getPlaylistTitle(ctx, yt, playlistID)
+	if err != nil {
+		log.Printf("Warning: %s", runWarnings.add(warnPlaylist, "", "Could not fetch title of playlist %s: %v", playlistID, err))
+		title = playlistID
+	}
+	c.titles[playlistID] = title
+	return title
+}
+
+// label returns the cached title of a playlist, or its ID if none is known.
+func (c *playlistTitleCache) label(playlistID string) string {
+	if title, ok := c.titles[playlistID]; ok {
+		return title
+	}
+	return playlistID
+}
+
+func getPlaylistTitle(ctx context.Context, yt *rotatingYouTubeService, playlistID string) (string, error) {
+	var response *youtube.PlaylistListResponse
+	err := yt.withKeyRotation(ctx, func(service *youtube.Service) error {
+		var err error
+		response, err = service.Playlists.List([]string{"snippet"}).Id(playlistID).Context(ctx).Do()
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("Playlists.List call failed for playlist %s: %w", playlistID, err)
+	}
+	if len(response.Items) == 0 || response.Items[0].Snippet == nil || response.Items[0].Snippet.Title == "" {
+		return "", fmt.Errorf("playlist %s not found", playlistID)
+	}
+	return response.Items[0].Snippet.Title, nil
+}
+
+// mergeVideoLists concatenates lists, dropping any video whose ID was already
+// seen so that each video is summarized once. A per-video word count from a
+// later list (-input-file) still carries over to the entry that was kept.
+func mergeVideoLists(lists ...[]VideoDetails) []VideoDetails {
+	seen := make(map[string]int) // Video ID to its index in merged
+	var merged []VideoDetails
+	for _, list := range lists {
+		for _, video := range list {
+			if i, ok := seen[video.ID]; ok {
+				if merged[i].WordCount == 0 {
+					merged[i].WordCount = video.WordCount
+				}
+				continue
+			}
+			seen[video.ID] = len(merged)
+			merged = append(merged, video)
+		}
+	}
+	return merged
+}
+
+// getChannelUploadsPlaylist returns the ID of a channel's uploads playlist.
+// channel is either a channel ID ("UC...") or a handle ("@name").
+func getChannelUploadsPlaylist(ctx context.Context, yt *rotatingYouTubeService, channel string) (string, error) {
+	var response *youtube.ChannelListResponse
+	err := yt.withKeyRotation(ctx, func(service *youtube.Service) error {
+		call := service.Channels.List([]string{"contentDetails"})
+		if strings.HasPrefix(channel, "@") {
+			call = call.ForHandle(channel)
+		} else {
+			call = call.Id(channel)
+		}
+		var err error
+		response, err = call.Context(ctx).Do()
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("Channels.List call failed for channel %s: %w", channel, err)
+	}
+	if len(response.Items) == 0 || response.Items[0].ContentDetails == nil || response.Items[0].ContentDetails.RelatedPlaylists == nil {
+		return "", fmt.Errorf("channel %s not found", channel)
+	}
+	return response.Items[0].ContentDetails.RelatedPlaylists.Uploads, nil
+}
+
+// getVideosByID looks up the details of individual videos. IDs that do not
+// resolve to a video are logged and skipped.
+func getVideosByID(ctx context.Context, yt *rotatingYouTubeService, ids []string) ([]VideoDetails, error) {
+	var videos []VideoDetails
+	for start := 0; start < len(ids); {
+		end := min(start+maxVideosPerRequest, len(ids))
+		batch := ids[start:end]
+		var response *youtube.VideoListResponse
+		err := yt.withKeyRotation(ctx, func(service *youtube.Service) error {
+			var err error
+			response, err = service.Videos.List([]string{"snippet"}).Id(batch...).Context(ctx).Do()
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Videos.List call failed: %w", err)
+		}
+
+		found := make(map[string]bool)
+		for _, item := range response.Items {
+			if item.Snippet == nil {
+				continue
+			}
+			found[item.Id] = true
+			video := VideoDetails{
+				ID:           item.Id,
+				Title:        item.Snippet.Title,
+				Description:  item.Snippet.Description,
+				ThumbnailURL: bestThumbnailURL(item.Snippet.Thumbnails, item.Id),
+			}
+			if publishedAt, err := time.Parse(time.RFC3339, item.Snippet.PublishedAt); err == nil {
+				video.PublishedAt = publishedAt
+			}
+			videos = append(videos, video)
+		}
+		for _, id := range batch {
+			if !found[id] {
+				log.Printf("Warning: %s", runWarnings.add(warnPlaylist, id, "Skipping video %s: not found or not public.", id))
+			}
+		}
+		start = end
+	}
+	return videos, nil
+}
+
+// readVideoIDsFile reads one video ID or URL per line. Blank lines and lines
+// starting with # are ignored.
+func readVideoIDsFile(path string) ([]string, map[string]int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open input file %s: %w", path, err)
+	}
+	defer file.Close()
+
+	var ids []string
+	wordCounts := make(map[string]int)
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line, count, hasCount := strings.Cut(line, ",")
+		id, err := parseVideoID(strings.TrimSpace(line))
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s:%d: %w", path, lineNumber, err)
+		}
+		if hasCount {
+			words, err := strconv.Atoi(strings.TrimSpace(count))
+			if err != nil || words <= 0 {
+				log.Printf("Warning: %s", runWarnings.add(warnConfig, id, "%s:%d: ignoring invalid word count %q for video %s; using -words.", path, lineNumber, count, id))
+			} else {
+				wordCounts[id] = words
+			}
+		}
+		ids = append(ids, id)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read input file %s: %w", path, err)
+	}
+	return ids, wordCounts, nil
+}
+
+// summaryWordCount is the number of words requested for video's summary: its
+// -input-file word count when set, otherwise -words.
+func summaryWordCount(video VideoDetails, cfg *AppConfig) int {
+	if video.WordCount > 0 {
+		return video.WordCount
+	}
+	return cfg.SummaryWordCount
+}
+
+// parseVideoID accepts a bare video ID or a watch, youtu.be or shorts URL.
+func parseVideoID(value string) (string, error) {
+	if !strings.Contains(value, "/") {
+		return value, nil
+	}
+	u, err := url.Parse(value)
+	if err != nil {
+		return "", fmt.Errorf("invalid video URL %q: %w", value, err)
+	}
+	if id := u.Query().Get("v"); id != "" {
+		return id, nil
+	}
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	switch {
+	case u.Host == "youtu.be" && len(segments) == 1 && segments[0] != "":
+		return segments[0], nil
+	case len(segments) == 2 && (segments[0] == "shorts" || segments[0] == "live" || segments[0] == "embed"):
+		return segments[1], nil
+	}
+	return "", fmt.Errorf("could not find a video ID in %q", value)
+}
+
+// personalPlaylists are the special playlist IDs that stand for the signed-in
+// user's own lists. The YouTube API only serves them with OAuth, so with an API
+// key they fail with an unhelpful error.
+var personalPlaylists = map[string]string{
+	"WL": "Watch Later",
+	"LL": "Liked videos",
+	"LM": "Liked music",
+	"HL": "Watch history",
+}
+
+// checkPersonalPlaylist explains why a personal playlist ID cannot be used.
+func checkPersonalPlaylist(playlistID string) error {
+	name, ok := personalPlaylists[strings.ToUpper(playlistID)]
+	if !ok {
+		return nil
+	}
+	return fmt.Errorf("playlist %s is your personal %q list, which YouTube only shares with OAuth sign-in; Summify authenticates with API keys, so add the videos to a public or unlisted playlist and use that ID instead", playlistID, name)
+}
+
+// Reasons a playlist item is left out of the run.
+const (
+	skipReasonPrivate        = "private"
+	skipReasonDeleted        = "deleted"
+	skipReasonMissingDetails = "missing details"
+)
+
+// skippedPlaylistItems counts the playlist items left out of a run by reason,
+// which explains a processed count lower than the playlist's displayed size.
+type skippedPlaylistItems map[string]int
+
+func (s skippedPlaylistItems) total() int {
+	total := 0
+	for _, count := range s {
+		total += count
+	}
+	return total
+}
+
+func (s skippedPlaylistItems) String() string {
+	var parts []string
+	for _, reason := range []string{skipReasonPrivate, skipReasonDeleted, skipReasonMissingDetails} {
+		if s[reason] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", s[reason], reason))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// playlistItemSkipReason returns why a playlist item cannot be processed, or
+// "" if it can. Private and deleted videos stay in playlists as placeholder
+// items that still carry the video ID.
+func playlistItemSkipReason(item *youtube.PlaylistItem) string {
+	if item.Snippet == nil || item.ContentDetails == nil || item.ContentDetails.VideoId == "" {
+		return skipReasonMissingDetails
+	}
+	if (item.Status != nil && item.Status.PrivacyStatus == "private") || item.Snippet.Title == "Private video" {
+		return skipReasonPrivate
+	}
+	if item.Snippet.Title == "Deleted video" {
+		return skipReasonDeleted
+	}
+	return ""
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/asticode/go-astisub"
+)
+
+// --- Speaker Labels ---
+
+const (
+	unnamedSpeaker = "Speaker"
+	speakerPrompt  = "\n\nThe transcript has one speaker turn per line, as \"Name: text\" (\"Speaker:\" when the captions do not name them). Attribute the key points to the speakers who made them and summarize each speaker's main contributions."
+)
+
+// speakerMarkerPattern matches the ">>" that captions use for a change of
+// speaker, with the optional name that may follow, e.g. ">> JOHN SMITH:".
+// A name is one to three capitalized (or all-caps) words, so a sentence that
+// happens to contain a colon, as in ">> so the point is: ...", is not taken
+// for one.
+var speakerMarkerPattern = regexp.MustCompile(`>>\s*(?:(\p{Lu}[\p{L}.'-]*(?: \p{Lu}[\p{L}.'-]*){0,2})\s*:)?`)
+
+// buildSpeakerTranscript puts each speaker turn on its own line prefixed
+// with the speaker's name, taken from ">> NAME:" markers in the caption text
+// or from VTT voice tags (<v Name>). A turn whose speaker is not named is
+// labelled unnamedSpeaker; text before the first marker is not labelled.
+func buildSpeakerTranscript(subs *astisub.Subtitles) string {
+	var turns []string
+	var current []string
+	speaker := ""
+	startTurn := func(name string) {
+		if len(current) > 0 {
+			turns = append(turns, formatSpeakerTurn(speaker, current))
+		}
+		speaker, current = name, nil
+	}
+	for _, item := range subs.Items {
+		for _, line := range item.Lines {
+			if voice := normalizeSpeakerName(line.VoiceName); voice != "" && voice != speaker {
+				startTurn(voice)
+			}
+			var text strings.Builder
+			for _, lineItem := range line.Items {
+				text.WriteString(lineItem.Text)
+				text.WriteString(" ")
+			}
+			rest := text.String()
+			markers := speakerMarkerPattern.FindAllStringSubmatchIndex(rest, -1)
+			if len(markers) == 0 {
+				current = append(current, strings.Fields(rest)...)
+				continue
+			}
+			current = append(current, strings.Fields(rest[:markers[0][0]])...)
+			for i, match := range markers {
+				name := unnamedSpeaker
+				if match[2] >= 0 {
+					name = normalizeSpeakerName(rest[match[2]:match[3]])
+				}
+				startTurn(name)
+				end := len(rest)
+				if i+1 < len(markers) {
+					end = markers[i+1][0]
+				}
+				current = append(current, strings.Fields(rest[match[1]:end])...)
+			}
+		}
+	}
+	startTurn("")
+	return strings.Join(turns, "\n")
+}
+
+func formatSpeakerTurn(speaker string, words []string) string {
+	if speaker == "" {
+		return strings.Join(words, " ")
+	}
+	return speaker + ": " + strings.Join(words, " ")
+}
+
+// normalizeSpeakerName collapses whitespace and turns all-caps names, as
+// broadcast captions write them, into title case: "JOHN  SMITH" becomes
+// "John Smith".
+func normalizeSpeakerName(name string) string {
+	name = strings.Join(strings.Fields(name), " ")
+	if name != strings.ToUpper(name) {
+		return name
+	}
+	runes := []rune(strings.ToLower(name))
+	for i, r := range runes {
+		if i == 0 || !unicode.IsLetter(runes[i-1]) && runes[i-1] != '\'' {
+			runes[i] = unicode.ToUpper(r)
+		}
+	}
+	return string(runes)
+}
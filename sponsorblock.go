@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/asticode/go-astisub"
+)
+
+// --- SponsorBlock Segments ---
+
+// sponsorBlockCategories are the SponsorBlock segment kinds -skip-sponsors
+// drops from the transcript.
+const sponsorBlockCategories = "sponsor,selfpromo,interaction,intro,outro"
+
+const sponsorBlockFileSuffix = ".sponsorblock.json"
+
+// sponsorSegment is one entry of yt-dlp's sponsorblock_chapters field.
+type sponsorSegment struct {
+	Start    float64 `json:"start_time"`
+	End      float64 `json:"end_time"`
+	Category string  `json:"category"`
+}
+
+// sponsorBlockArgs asks yt-dlp to look the video up in SponsorBlock and to
+// write the segments it found next to the subtitles. --sponsorblock-remove
+// only cuts downloaded media, so the segments are marked instead and the
+// matching cues are removed after parsing.
+func sponsorBlockArgs(dir string) []string {
+	return []string{
+		"--sponsorblock-mark", sponsorBlockCategories,
+		"--print-to-file", "video:%(sponsorblock_chapters)j", filepath.Join(dir, "%(id)s"+sponsorBlockFileSuffix),
+	}
+}
+
+// readSponsorSegments reads the segments yt-dlp wrote to path. A missing
+// file or yt-dlp's "NA" placeholder means no segments.
+func readSponsorSegments(path string) ([]sponsorSegment, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	text := strings.TrimSpace(string(data))
+	if text == "" || text == "NA" {
+		return nil, nil
+	}
+	var segments []sponsorSegment
+	if err := json.Unmarshal([]byte(text), &segments); err != nil {
+		return nil, fmt.Errorf("failed to parse SponsorBlock segments %s: %w", path, err)
+	}
+	return segments, nil
+}
+
+// removeSponsoredCues drops the cues whose midpoint falls inside a segment
+// and returns how many were removed.
+func removeSponsoredCues(subs *astisub.Subtitles, segments []sponsorSegment) int {
+	if len(segments) == 0 {
+		return 0
+	}
+	kept := subs.Items[:0]
+	for _, item := range subs.Items {
+		mid := (item.StartAt + item.EndAt) / 2
+		sponsored := false
+		for _, segment := range segments {
+			if mid >= secondsToDuration(segment.Start) && mid < secondsToDuration(segment.End) {
+				sponsored = true
+				break
+			}
+		}
+		if !sponsored {
+			kept = append(kept, item)
+		}
+	}
+	removed := len(subs.Items) - len(kept)
+	subs.Items = kept
+	return removed
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"time"
+)
+
+// --- Run Statistics ---
+
+// printRunStats writes the -stats-only report: videos per status, per-video
+// processing times, Gemini token usage and the spread of transcript lengths.
+func printRunStats(w io.Writer, videos []VideoDetails, allResults map[string]ProcessingResult, usage *usageTracker, elapsed time.Duration) {
+	var statuses []string
+	counts := make(map[string]int)
+	var durations []time.Duration
+	var lengths []int
+	for _, video := range videos {
+		result, ok := allResults[video.ID]
+		status := resultStatus(result, ok)
+		if counts[status] == 0 {
+			statuses = append(statuses, status)
+		}
+		counts[status]++
+		if !ok {
+			continue
+		}
+		durations = append(durations, result.Elapsed)
+		if result.TranscriptChars > 0 {
+			lengths = append(lengths, result.TranscriptChars)
+		}
+	}
+
+	fmt.Fprintln(w, "\n--- Run Statistics ---")
+	fmt.Fprintf(w, "Videos: %d\n", len(videos))
+	for _, status := range statuses {
+		fmt.Fprintf(w, "  %s: %d\n", status, counts[status])
+	}
+	fmt.Fprintf(w, "Run time: %v\n", elapsed.Round(time.Second))
+	if len(durations) > 0 {
+		slices.Sort(durations)
+		fmt.Fprintf(w, "Per-video time: min %v, median %v, p90 %v, max %v\n",
+			durations[0].Round(time.Millisecond), percentile(durations, 50).Round(time.Millisecond),
+			percentile(durations, 90).Round(time.Millisecond), durations[len(durations)-1].Round(time.Millisecond))
+	}
+	if usage != nil {
+		calls, input, output := usage.tokens()
+		fmt.Fprintf(w, "Gemini calls: %d, input tokens: %d, output tokens: %d\n", calls, input, output)
+	}
+	if len(lengths) > 0 {
+		slices.Sort(lengths)
+		fmt.Fprintf(w, "Transcript length (characters): min %d, median %d, p90 %d, max %d\n",
+			lengths[0], percentile(lengths, 50), percentile(lengths, 90), lengths[len(lengths)-1])
+	}
+	fmt.Fprintln(w, "--- End of Statistics ---")
+}
+
+// percentile returns the p-th percentile (nearest rank) of sorted values,
+// which must not be empty.
+func percentile[T any](sorted []T, p int) T {
+	rank := (p*len(sorted) + 99) / 100
+	return sorted[max(rank, 1)-1]
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// --- Structured Summaries ---
+
+// Extract is a summary in the fixed shape requested by -structured.
+type Extract struct {
+	Title     string   `json:"title"`
+	OneLiner  string   `json:"one_liner"`
+	KeyPoints []string `json:"key_points"`
+	Topics    []string `json:"topics"`
+	Sentiment string   `json:"sentiment"`
+	Prefix    string   `json:"prefix,omitempty"` // Rendered -summary-prefix, set only on output
+	Suffix    string   `json:"suffix,omitempty"` // Rendered -summary-suffix, set only on output
+}
+
+var extractSentiments = []string{"positive", "neutral", "negative", "mixed"}
+
+// extractSchema is the response schema Gemini is held to under -structured.
+var extractSchema = &genai.Schema{
+	Type: genai.TypeObject,
+	Properties: map[string]*genai.Schema{
+		"title":      {Type: genai.TypeString, Description: "A short descriptive title for the video"},
+		"one_liner":  {Type: genai.TypeString, Description: "The video summed up in one sentence"},
+		"key_points": {Type: genai.TypeArray, Items: &genai.Schema{Type: genai.TypeString}, Description: "The main points, one sentence each"},
+		"topics":     {Type: genai.TypeArray, Items: &genai.Schema{Type: genai.TypeString}, Description: "Short topic tags"},
+		"sentiment":  {Type: genai.TypeString, Enum: extractSentiments, Description: "The overall tone of the video"},
+	},
+	Required: []string{"title", "one_liner", "key_points", "topics", "sentiment"},
+}
+
+const structuredPrompt = "\n\nReturn the summary as a JSON object with a short descriptive \"title\", a one-sentence \"one_liner\", the main \"key_points\" as one-sentence strings, short \"topics\" tags and the overall \"sentiment\" (positive, neutral, negative or mixed)."
+
+// parseExtract decodes a -structured response and checks that every field
+// is filled in and the sentiment is one of extractSentiments.
+func parseExtract(text string) (*Extract, error) {
+	var extract Extract
+	if err := json.Unmarshal([]byte(strings.TrimSpace(text)), &extract); err != nil {
+		return nil, fmt.Errorf("malformed structured summary: %w", err)
+	}
+	extract.Title = strings.TrimSpace(extract.Title)
+	extract.OneLiner = strings.TrimSpace(extract.OneLiner)
+	extract.Sentiment = strings.ToLower(strings.TrimSpace(extract.Sentiment))
+	var missing []string
+	if extract.Title == "" {
+		missing = append(missing, "title")
+	}
+	if extract.OneLiner == "" {
+		missing = append(missing, "one_liner")
+	}
+	if len(extract.KeyPoints) == 0 {
+		missing = append(missing, "key_points")
+	}
+	if len(extract.Topics) == 0 {
+		missing = append(missing, "topics")
+	}
+	if extract.Sentiment == "" {
+		missing = append(missing, "sentiment")
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("structured summary is missing %s", strings.Join(missing, ", "))
+	}
+	if !slices.Contains(extractSentiments, extract.Sentiment) {
+		return nil, fmt.Errorf("structured summary has sentiment %q, want one of %s", extract.Sentiment, strings.Join(extractSentiments, ", "))
+	}
+	return &extract, nil
+}
+
+// summarizeStructured asks Gemini for the transcript's summary as an
+// Extract, retrying once if the response is malformed or incomplete.
+func summarizeStructured(ctx context.Context, gemini *rotatingGeminiModel, video VideoDetails, transcript string, chapters []Chapter, template string, cfg *AppConfig) (*Extract, int, error) {
+	prompt := buildSummaryPrompt(transcript, chapters, modelPromptTemplate(gemini.modelName, template, cfg), cfg)
+	totalAttempts := 0
+	var lastErr error
+	for try := 1; try <= 2; try++ {
+		text, attempts, err := generateWithGeminiSchema(ctx, gemini, prompt, extractSchema, cfg)
+		totalAttempts += attempts
+		if err != nil && !isTruncatedResponse(err) {
+			return nil, totalAttempts, err
+		}
+		extract, parseErr := parseExtract(text)
+		if parseErr == nil {
+			return extract, totalAttempts, nil
+		}
+		if err != nil {
+			parseErr = fmt.Errorf("%w (%v)", parseErr, err)
+		}
+		lastErr = parseErr
+		if try == 1 {
+			log.Printf("  Video %s (%s): %v; retrying once.", video.ID, video.Title, parseErr)
+		}
+	}
+	return nil, totalAttempts, lastErr
+}
+
+// renderExtract writes an Extract out as plain text, which stands in as the
+// video's Summary for the report, index and notes.
+func renderExtract(extract *Extract) string {
+	var b strings.Builder
+	b.WriteString(extract.OneLiner)
+	b.WriteString("\n")
+	for _, point := range extract.KeyPoints {
+		fmt.Fprintf(&b, "\n- %s", strings.TrimSpace(point))
+	}
+	fmt.Fprintf(&b, "\n\nTopics: %s\nSentiment: %s", strings.Join(extract.Topics, ", "), extract.Sentiment)
+	return b.String()
+}
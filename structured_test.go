@@ -0,0 +1,28 @@
+package main
+
+import "testing"
+
+func TestParseExtract(t *testing.T) {
+	tests := []struct {
+		name          string
+		text          string
+		wantSentiment string
+		wantErr       bool
+	}{
+		{"valid", `{"title":"T","one_liner":"O","key_points":["k"],"topics":["t"],"sentiment":" Mixed "}`, "mixed", false},
+		{"unknown sentiment", `{"title":"T","one_liner":"O","key_points":["k"],"topics":["t"],"sentiment":"upbeat"}`, "", true},
+		{"missing field", `{"title":"T","one_liner":"O","key_points":[],"topics":["t"],"sentiment":"neutral"}`, "", true},
+		{"malformed", `{"title":`, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			extract, err := parseExtract(tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseExtract() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && extract.Sentiment != tt.wantSentiment {
+				t.Errorf("Sentiment = %q, want %q", extract.Sentiment, tt.wantSentiment)
+			}
+		})
+	}
+}
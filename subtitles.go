@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/asticode/go-astisub"
+)
+
+// --- Subtitle File Parsing ---
+
+// openSubtitles parses a VTT or SRT file with astisub. Unless strict is set,
+// a file astisub rejects is read again with parseSubtitlesLeniently, which
+// recovers the cue text from most malformed archival captions.
+func openSubtitles(path string, strict bool) (*astisub.Subtitles, error) {
+	subs, err := astisub.OpenFile(path)
+	if err == nil || strict {
+		return subs, err
+	}
+	log.Printf("Warning: %s", runWarnings.add(warnTranscript, "", "%s is malformed (%v); falling back to lenient parsing (see -strict-parse).", path, err))
+	return parseSubtitlesLeniently(path)
+}
+
+var (
+	cueTimingPattern = regexp.MustCompile(`^\s*([\d:.,]+)\s*-->\s*([\d:.,]+)`)
+	cueTagPattern    = regexp.MustCompile(`<[^>]*>`)
+)
+
+// parseSubtitlesLeniently extracts cues line by line with scanCues,
+// tolerating headers, NOTE/STYLE blocks, SRT counters, markup tags and
+// timings that do not parse.
+func parseSubtitlesLeniently(path string) (*astisub.Subtitles, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	subs := astisub.NewSubtitles()
+	var current *astisub.Item
+	err = scanCues(file, func(start, end time.Duration) {
+		current = &astisub.Item{StartAt: start, EndAt: end}
+		subs.Items = append(subs.Items, current)
+	}, func(text string) {
+		current.Lines = append(current.Lines, astisub.Line{Items: []astisub.LineItem{{Text: text}}})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(subs.Items) == 0 {
+		return nil, fmt.Errorf("no cues found in %s", path)
+	}
+	return subs, nil
+}
+
+// scanCues reads subtitles a line at a time: a timing line ("start --> end")
+// starts a cue and the following lines up to a blank line are its text,
+// passed to text with markup tags removed. Lines outside a cue are skipped.
+func scanCues(r io.Reader, cue func(start, end time.Duration), text func(string)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxSubtitleLineBytes)
+	inCue := false
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if match := cueTimingPattern.FindStringSubmatch(line); match != nil {
+			cue(parseCueTime(match[1]), parseCueTime(match[2]))
+			inCue = true
+			continue
+		}
+		if line == "" {
+			inCue = false
+			continue
+		}
+		if !inCue {
+			continue // Header, NOTE/STYLE block or SRT counter
+		}
+		if cleaned := strings.TrimSpace(cueTagPattern.ReplaceAllString(line, "")); cleaned != "" {
+			text(cleaned)
+		}
+	}
+	return scanner.Err()
+}
+
+// maxSubtitleLineBytes bounds a single subtitle line for scanCues.
+const maxSubtitleLineBytes = 1 << 20
+
+// streamPlainTranscript builds the plain transcript (see
+// buildPlainTranscript) while reading the file, for -stream-subtitles. Only
+// the transcript text is held in memory, not the parsed cues.
+func streamPlainTranscript(path, join string) (transcript string, cues int, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+	var b strings.Builder
+	err = scanCues(file, func(time.Duration, time.Duration) {
+		if cues > 0 && join != transcriptJoinNewline {
+			b.WriteString(" ") // buildPlainTranscript's extra space between cues
+		}
+		cues++
+	}, func(text string) {
+		if join == transcriptJoinNewline {
+			b.WriteString(strings.Join(strings.Fields(text), " "))
+			b.WriteString("\n")
+			return
+		}
+		b.WriteString(text)
+		b.WriteString(" ")
+	})
+	if err != nil {
+		return "", cues, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return strings.TrimSpace(b.String()), cues, nil
+}
+
+// streamingSubtitles reports whether -stream-subtitles applies. Timestamped,
+// speaker, compact and SponsorBlock-filtered transcripts need the cue
+// timings and structure, so those modes still parse with astisub.
+func streamingSubtitles(cfg *AppConfig) bool {
+	return cfg.StreamSubtitles && !cfg.Cite && !cfg.PreserveSpeakers && !cfg.CompactTranscript && !cfg.SkipSponsors
+}
+
+// parseCueTime reads "hh:mm:ss.mmm", "mm:ss.mmm" or the SRT comma form,
+// returning 0 when the value does not parse.
+func parseCueTime(value string) time.Duration {
+	var seconds float64
+	for _, part := range strings.Split(strings.ReplaceAll(value, ",", "."), ":") {
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return 0
+		}
+		seconds = seconds*60 + n
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// totalAllocated returns the bytes allocated on the heap so far, for
+// measuring what reading a subtitle file costs. Other workers allocate at
+// the same time, so with -max-concurrency above 1 it is an upper bound.
+func totalAllocated() uint64 {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.TotalAlloc
+}
+
+// peakMemory returns the process's peak resident set size, read from
+// /proc/self/status (VmHWM). ok is false where that is unavailable.
+func peakMemory() (bytes uint64, ok bool) {
+	data, err := os.ReadFile("/proc/self/status")
+	if err != nil {
+		return 0, false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if value, found := strings.CutPrefix(line, "VmHWM:"); found {
+			kib, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimSpace(value), " kB"), 10, 64)
+			return kib * 1024, err == nil
+		}
+	}
+	return 0, false
+}
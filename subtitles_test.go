@@ -0,0 +1,99 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/asticode/go-astisub"
+)
+
+const testVTT = `WEBVTT
+Kind: captions
+Language: en
+
+00:00:01.000 --> 00:00:02.000
+hello
+
+00:00:02.000 --> 00:00:03.500
+world
+
+00:00:04.000 --> 00:00:06.000
+second   line
+and more
+`
+
+func writeTestVTT(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "abc.en.vtt")
+	if err := os.WriteFile(path, []byte(testVTT), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestBuildPlainTranscriptJoin(t *testing.T) {
+	subs, err := astisub.OpenFile(writeTestVTT(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		join string
+		want string
+	}{
+		{transcriptJoinSpace, "hello  world  second   line and more"},
+		{transcriptJoinNewline, "hello\nworld\nsecond line\nand more"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.join, func(t *testing.T) {
+			if got := buildPlainTranscript(subs, tt.join); got != tt.want {
+				t.Errorf("buildPlainTranscript(%q) = %q, want %q", tt.join, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStreamPlainTranscriptMatchesAstisub(t *testing.T) {
+	path := writeTestVTT(t)
+	subs, err := astisub.OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, join := range []string{transcriptJoinSpace, transcriptJoinNewline} {
+		t.Run(join, func(t *testing.T) {
+			streamed, cues, err := streamPlainTranscript(path, join)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := buildPlainTranscript(subs, join); streamed != want {
+				t.Errorf("streamPlainTranscript(%q) = %q, want %q", join, streamed, want)
+			}
+			if cues != len(subs.Items) {
+				t.Errorf("streamPlainTranscript counted %d cues, want %d", cues, len(subs.Items))
+			}
+		})
+	}
+}
+
+func TestSpeakerMarkerPatternName(t *testing.T) {
+	tests := []struct {
+		text string
+		want string // "" when no name is captured
+	}{
+		{">> JOHN SMITH: hello", "JOHN SMITH"},
+		{">> Dr. Jane O'Neil: hello", "Dr. Jane O'Neil"},
+		{">>Anna: hi", "Anna"},
+		{">> so the point is: this", ""},
+		{">> The Four Big Ideas: here", ""},
+		{">> hello there", ""},
+	}
+	for _, tt := range tests {
+		match := speakerMarkerPattern.FindStringSubmatch(tt.text)
+		if match == nil {
+			t.Fatalf("speakerMarkerPattern did not match %q", tt.text)
+		}
+		if match[1] != tt.want {
+			t.Errorf("name in %q = %q, want %q", tt.text, match[1], tt.want)
+		}
+	}
+}
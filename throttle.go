@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- Adaptive Concurrency ---
+
+// concurrencyLimiter bounds how many videos are processed at once and adapts
+// the bound AIMD-style: it halves on rate-limit errors (never below min) and
+// grows by one after a full limit's worth of clean videos (never above max).
+type concurrencyLimiter struct {
+	mu        sync.Mutex
+	cond      *sync.Cond
+	limit     int
+	min, max  int
+	inUse     int
+	successes int // Clean videos since the last change of limit
+}
+
+func newConcurrencyLimiter(min, max int) *concurrencyLimiter {
+	l := &concurrencyLimiter{limit: max, min: min, max: max}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+// acquire waits for a free slot, or returns ctx's error if it ends first.
+func (l *concurrencyLimiter) acquire(ctx context.Context) error {
+	stop := context.AfterFunc(ctx, func() {
+		l.mu.Lock()
+		l.cond.Broadcast()
+		l.mu.Unlock()
+	})
+	defer stop()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for l.inUse >= l.limit {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		l.cond.Wait()
+	}
+	l.inUse++
+	return nil
+}
+
+func (l *concurrencyLimiter) release() {
+	l.mu.Lock()
+	l.inUse--
+	l.cond.Broadcast()
+	l.mu.Unlock()
+}
+
+// reportRateLimit halves the limit after a video ran into rate limiting.
+func (l *concurrencyLimiter) reportRateLimit() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.successes = 0
+	if reduced := max(l.min, l.limit/2); reduced < l.limit {
+		log.Printf("Throttle: rate limiting detected; reducing concurrency from %d to %d.", l.limit, reduced)
+		l.limit = reduced
+	}
+}
+
+// reportSuccess counts a cleanly processed video and raises the limit by one
+// once a full limit's worth of them finished since the last change.
+func (l *concurrencyLimiter) reportSuccess() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.successes++
+	if l.successes >= l.limit && l.limit < l.max {
+		l.successes = 0
+		l.limit++
+		log.Printf("Throttle: calls are succeeding; raising concurrency to %d.", l.limit)
+		l.cond.Broadcast()
+	}
+}
+
+// wasRateLimited reports whether processing a video ran into rate limiting:
+// a Google API call backed off after an HTTP 429, a quota error from a
+// Google API, or an HTTP 429 from yt-dlp. Retries on another key after a
+// key's daily quota ran out are not counted, since fewer workers would not
+// have avoided them.
+func wasRateLimited(result ProcessingResult) bool {
+	if result.RateLimited {
+		return true
+	}
+	if result.Err == nil || errors.Is(result.Err, errNoTranscript) {
+		return false
+	}
+	if isQuotaExceededError(result.Err) || isRateLimitError(result.Err) {
+		return true
+	}
+	message := strings.ToLower(result.Err.Error())
+	return strings.Contains(message, "http error 429") || strings.Contains(message, "too many requests")
+}
+
+// --- Output Rate Limit ---
+
+// tokenBucket paces deliveries to external sinks for -output-rate: it holds
+// up to burst tokens, refilled at rate per second, and each delivery takes
+// one. It is used by a single goroutine and is not safe for concurrent use.
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	return &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// wait takes a token, sleeping until one is available, and returns how long
+// it slept. A nil bucket never waits.
+func (b *tokenBucket) wait() time.Duration {
+	if b == nil {
+		return 0
+	}
+	now := time.Now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	delay := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+	time.Sleep(delay)
+	b.tokens, b.last = 0, now.Add(delay)
+	return delay
+}
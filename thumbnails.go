@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"google.golang.org/api/youtube/v3"
+)
+
+// --- Thumbnails ---
+
+// thumbnailURLFormat is the well-known thumbnail location for a video ID, used
+// when the playlist item did not include thumbnail details.
+const thumbnailURLFormat = "https://i.ytimg.com/vi/%s/hqdefault.jpg"
+
+// bestThumbnailURL returns the highest resolution thumbnail listed in
+// thumbnails, falling back to the URL derived from the video ID.
+func bestThumbnailURL(thumbnails *youtube.ThumbnailDetails, videoID string) string {
+	if thumbnails != nil {
+		for _, thumbnail := range []*youtube.Thumbnail{thumbnails.Maxres, thumbnails.Standard, thumbnails.High, thumbnails.Medium, thumbnails.Default} {
+			if thumbnail != nil && thumbnail.Url != "" {
+				return thumbnail.Url
+			}
+		}
+	}
+	return fmt.Sprintf(thumbnailURLFormat, videoID)
+}
+
+// downloadThumbnail saves the video's thumbnail as <dir>/<videoID>.jpg and
+// returns the local path. A partially written file is removed on failure.
+func downloadThumbnail(ctx context.Context, client *http.Client, video VideoDetails, dir string, perm os.FileMode) (string, error) {
+	thumbnailURL := video.ThumbnailURL
+	if thumbnailURL == "" {
+		thumbnailURL = fmt.Sprintf(thumbnailURLFormat, video.ID)
+	}
+	if err := makeDir(dir, perm); err != nil {
+		return "", fmt.Errorf("failed to create thumbnail dir %s: %w", dir, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, thumbnailURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("invalid thumbnail URL %s: %w", thumbnailURL, err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("thumbnail download for video %s failed: %w", video.ID, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("thumbnail download for video %s failed: %s", video.ID, resp.Status)
+	}
+
+	path := filepath.Join(dir, video.ID+".jpg")
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, filePerm(perm))
+	if err != nil {
+		return "", fmt.Errorf("failed to create thumbnail file %s: %w", path, err)
+	}
+	if _, err := io.Copy(file, resp.Body); err != nil {
+		file.Close()
+		os.Remove(path)
+		return "", fmt.Errorf("failed to write thumbnail file %s: %w", path, err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(path)
+		return "", fmt.Errorf("failed to write thumbnail file %s: %w", path, err)
+	}
+	return path, nil
+}
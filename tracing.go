@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- OpenTelemetry Tracing ---
+
+// tracer records spans for the run and exports them to an OTLP/HTTP collector
+// (JSON encoding) when the run ends. A nil tracer and its nil spans are
+// no-ops, so tracing costs nothing without -otel-endpoint.
+type tracer struct {
+	url    string
+	client *http.Client
+
+	mu    sync.Mutex
+	spans []otlpSpan
+}
+
+// span is an open span. Its parent is the span stored in the context passed
+// to tracer.start.
+type span struct {
+	tracer *tracer
+	data   otlpSpan
+	start  time.Time
+}
+
+type spanContextKey struct{}
+
+// OTLP/HTTP JSON payload, trimmed to the fields Summify sets.
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            *otlpStatus     `json:"status,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string         `json:"key"`
+	Value map[string]any `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"` // 2 is STATUS_CODE_ERROR
+	Message string `json:"message,omitempty"`
+}
+
+const otlpSpanKindInternal = 1
+
+// newTracer returns a tracer exporting to endpoint, or nil when endpoint is
+// empty. A bare collector address such as http://localhost:4318 gets the
+// standard /v1/traces path.
+func newTracer(endpoint string, timeout time.Duration) *tracer {
+	if endpoint == "" {
+		return nil
+	}
+	url := strings.TrimRight(endpoint, "/")
+	if !strings.HasSuffix(url, "/v1/traces") {
+		url += "/v1/traces"
+	}
+	return &tracer{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+// start opens a span named name as a child of the span in ctx, if any, and
+// returns a context carrying the new span.
+func (t *tracer) start(ctx context.Context, name string) (context.Context, *span) {
+	if t == nil {
+		return ctx, nil
+	}
+	s := &span{tracer: t, start: time.Now(), data: otlpSpan{Name: name, Kind: otlpSpanKindInternal, SpanID: randomHex(8)}}
+	if parent, ok := ctx.Value(spanContextKey{}).(*span); ok {
+		s.data.TraceID = parent.data.TraceID
+		s.data.ParentSpanID = parent.data.SpanID
+	} else {
+		s.data.TraceID = randomHex(16)
+	}
+	return context.WithValue(ctx, spanContextKey{}, s), s
+}
+
+// startChild opens a span named name as a child of the span in ctx, using
+// that span's tracer, so code below the worker can add spans without being
+// handed the tracer. Without a span in ctx it is a no-op.
+func startChild(ctx context.Context, name string) (context.Context, *span) {
+	parent, ok := ctx.Value(spanContextKey{}).(*span)
+	if !ok {
+		return ctx, nil
+	}
+	return parent.tracer.start(ctx, name)
+}
+
+// setAttribute records a string, int or bool attribute on the span.
+func (s *span) setAttribute(key string, value any) {
+	if s == nil {
+		return
+	}
+	var v map[string]any
+	switch value := value.(type) {
+	case int:
+		v = map[string]any{"intValue": strconv.Itoa(value)}
+	case bool:
+		v = map[string]any{"boolValue": value}
+	default:
+		v = map[string]any{"stringValue": fmt.Sprint(value)}
+	}
+	s.data.Attributes = append(s.data.Attributes, otlpAttribute{Key: key, Value: v})
+}
+
+// setError marks the span as failed when err is not nil.
+func (s *span) setError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.data.Status = &otlpStatus{Code: 2, Message: err.Error()}
+}
+
+// end closes the span and queues it for export.
+func (s *span) end() {
+	if s == nil {
+		return
+	}
+	s.data.StartTimeUnixNano = strconv.FormatInt(s.start.UnixNano(), 10)
+	s.data.EndTimeUnixNano = strconv.FormatInt(time.Now().UnixNano(), 10)
+	s.tracer.mu.Lock()
+	s.tracer.spans = append(s.tracer.spans, s.data)
+	s.tracer.mu.Unlock()
+}
+
+// flush exports every ended span in one OTLP request.
+func (t *tracer) flush(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	spans := t.spans
+	t.spans = nil
+	t.mu.Unlock()
+	if len(spans) == 0 {
+		return nil
+	}
+
+	payload := map[string]any{
+		"resourceSpans": []any{map[string]any{
+			"resource": map[string]any{"attributes": []otlpAttribute{
+				{Key: "service.name", Value: map[string]any{"stringValue": "summify"}},
+			}},
+			"scopeSpans": []any{map[string]any{
+				"scope": map[string]any{"name": "summify"},
+				"spans": spans,
+			}},
+		}},
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to export %d spans to %s: %w", len(spans), t.url, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("failed to export %d spans to %s: %s", len(spans), t.url, resp.Status)
+	}
+	return nil
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
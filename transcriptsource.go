@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// --- Transcript Sources ---
+
+// Values accepted by -transcript-source.
+const (
+	transcriptSourceYtDlp = "yt-dlp"
+	transcriptSourceFiles = "files"
+)
+
+// TranscriptSource produces the plain-text transcript of a video. attempts is
+// how many fetches it took, for the per-video retry counts in the report.
+type TranscriptSource interface {
+	Fetch(ctx context.Context, video VideoDetails) (transcript string, attempts int, err error)
+}
+
+// ytDlpTranscriptSource downloads subtitles with yt-dlp, honouring
+// -caption-wait for recent uploads.
+type ytDlpTranscriptSource struct {
+	cfg *AppConfig
+}
+
+func (s ytDlpTranscriptSource) Fetch(ctx context.Context, video VideoDetails) (string, int, error) {
+	return getTranscriptWaitingForCaptions(ctx, video, s.cfg)
+}
+
+// fileTranscriptSource reads transcripts saved as <dir>/<videoID>.txt.
+type fileTranscriptSource struct {
+	dir string
+}
+
+func (s fileTranscriptSource) Fetch(ctx context.Context, video VideoDetails) (string, int, error) {
+	transcript, err := readStoredTranscript(s.dir, video.ID)
+	return transcript, 1, err
+}
+
+// newTranscriptSource returns the source selected by -transcript-source. By
+// default transcripts are read from disk with -from-transcripts and fetched
+// with yt-dlp otherwise. The files source reads from the -from-transcripts
+// directory, or else from the -keep-transcripts directory of earlier runs.
+// -local-file always uses its own ffmpeg-based source.
+func newTranscriptSource(cfg *AppConfig) (TranscriptSource, error) {
+	if cfg.LocalFile != "" {
+		if cfg.TranscriptSource != "" {
+			return nil, fmt.Errorf("-transcript-source cannot be used with -local-file")
+		}
+		return localFileTranscriptSource{path: cfg.LocalFile, cfg: cfg}, nil
+	}
+	name := cfg.TranscriptSource
+	if name == "" {
+		name = transcriptSourceYtDlp
+		if cfg.FromTranscriptsDir != "" {
+			name = transcriptSourceFiles
+		}
+	}
+	switch name {
+	case transcriptSourceYtDlp:
+		return ytDlpTranscriptSource{cfg: cfg}, nil
+	case transcriptSourceFiles:
+		dir := cfg.FromTranscriptsDir
+		if dir == "" {
+			dir = cfg.KeepTranscriptsDir
+		}
+		if dir == "" {
+			return nil, fmt.Errorf("-transcript-source %s needs -from-transcripts or -keep-transcripts to name the directory", transcriptSourceFiles)
+		}
+		if cfg.TranscriptsOnly {
+			return nil, fmt.Errorf("-transcript-source %s cannot be used with -transcripts-only", transcriptSourceFiles)
+		}
+		return fileTranscriptSource{dir: dir}, nil
+	default:
+		return nil, fmt.Errorf("invalid -transcript-source %q: must be %s or %s", name, transcriptSourceYtDlp, transcriptSourceFiles)
+	}
+}
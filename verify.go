@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// --- Summary Verification ---
+
+const (
+	verifyPromptFormat    = "Below are a transcript and a summary of it. Check each factual claim in the summary against the transcript. List every claim that the transcript does not support, or that it contradicts, quoting or closely paraphrasing the claim from the summary. Do not list claims that the transcript supports, even if worded differently. Return an empty list if every claim is supported.\n\n[Transcript]\n%s\n[End of transcript]\n\n[Summary]\n%s\n[End of summary]"
+	defaultVerifyMaxChars = 100000
+)
+
+// verifySchema is the response schema for the -verify call.
+var verifySchema = &genai.Schema{
+	Type: genai.TypeObject,
+	Properties: map[string]*genai.Schema{
+		"unsupported_claims": {Type: genai.TypeArray, Items: &genai.Schema{Type: genai.TypeString}, Description: "Claims in the summary that the transcript does not support"},
+	},
+	Required: []string{"unsupported_claims"},
+}
+
+// verifySummary asks Gemini which claims in summary the transcript does not
+// support, for -verify. The returned list is empty, not nil, when every
+// claim is supported.
+func verifySummary(ctx context.Context, gemini *rotatingGeminiModel, transcript, summary string, cfg *AppConfig) ([]string, error) {
+	response, _, err := generateWithGeminiSchema(ctx, gemini, fmt.Sprintf(verifyPromptFormat, transcript, summary), verifySchema, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return parseVerification(response)
+}
+
+// parseVerification reads the verifier's reply. A reply that is not the
+// expected JSON object is an error rather than a pass, so a confused
+// verifier never makes a summary look checked.
+func parseVerification(response string) ([]string, error) {
+	response = strings.TrimSpace(response)
+	response = strings.TrimPrefix(response, "```json")
+	response = strings.TrimSuffix(strings.TrimPrefix(response, "```"), "```")
+	var reply struct {
+		UnsupportedClaims *[]string `json:"unsupported_claims"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(response)), &reply); err != nil {
+		return nil, fmt.Errorf("malformed verifier reply %q: %w", previewText(response, 20), err)
+	}
+	if reply.UnsupportedClaims == nil {
+		return nil, fmt.Errorf("verifier reply has no unsupported_claims list")
+	}
+	claims := []string{}
+	for _, claim := range *reply.UnsupportedClaims {
+		if claim = strings.TrimSpace(claim); claim != "" {
+			claims = append(claims, claim)
+		}
+	}
+	return claims, nil
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// --- Video Worker ---
+
+// videoWorker holds what the workers for every video in a run share.
+type videoWorker struct {
+	source          TranscriptSource
+	youtube         *rotatingYouTubeService // nil for sources that need no YouTube API
+	comparedModels  []comparedModel         // -compare-models; nil otherwise
+	tracer          *tracer
+	limiter         *concurrencyLimiter
+	chain           *seriesChain // -series-context; nil otherwise
+	thumbnailClient *http.Client
+}
+
+// process fetches, summarizes and post-processes one video and returns its
+// result. currentGeminiClient is nil when summarization is unavailable.
+func (w *videoWorker) process(ctx context.Context, v VideoDetails, currentCfg *AppConfig, currentGeminiClient *rotatingGeminiModel) (result ProcessingResult) {
+	log.Printf("Video %s (%s): Worker started.", v.ID, v.Title)
+	started := time.Now()
+	if v.WordCount > 0 {
+		videoCfg := *currentCfg
+		videoCfg.SummaryWordCount = v.WordCount
+		currentCfg = &videoCfg
+		log.Printf("Video %s (%s): Using a %d-word summary from the input file.", v.ID, v.Title, v.WordCount)
+	}
+	// Initialize ProcessingResult with VideoDetails
+	currentProcessingResult := ProcessingResult{VideoDetails: v}
+	if fromID, previousSummary := w.chain.context(v.ID); previousSummary != "" {
+		videoCfg := *currentCfg
+		videoCfg.PreviousSummary = previousSummary
+		currentCfg = &videoCfg
+		currentProcessingResult.ContextFrom = fromID
+		log.Printf("Video %s (%s): Using the summary of %s as series context.", v.ID, v.Title, fromID)
+	}
+	if currentCfg.ManifestPath != "" {
+		currentProcessingResult.Manifest = &manifestEntry{VideoID: v.ID, Title: v.Title, TranscriptSource: transcriptSourceName(w.source), StartedAt: started}
+	}
+	var trail *explainTrail
+	if currentCfg.Explain {
+		trail = &explainTrail{}
+		defer func() {
+			trail.note("status", "%s", resultStatus(currentProcessingResult, true))
+			if currentProcessingResult.Err != nil {
+				trail.note("error", "%v", currentProcessingResult.Err)
+			}
+			trail.note("attempts", "transcript %d, summary %d", currentProcessingResult.TranscriptAttempts, currentProcessingResult.LLMAttempts)
+			trail.note("elapsed", "%v", time.Since(started).Round(time.Millisecond))
+			trail.print(v)
+		}()
+	}
+	ctx = withExplainTrail(ctx, trail)
+	facts := &videoFacts{}
+	ctx = withVideoFacts(ctx, facts)
+	// Callees get the video span's context, so their spans (such as each
+	// Gemini call's) nest under it.
+	ctx, videoSpan := w.tracer.start(ctx, "summify.video")
+	videoSpan.setAttribute("video.id", v.ID)
+	videoSpan.setAttribute("video.title", v.Title)
+	// This runs once the result is returned, so it sees the final result.
+	defer func() {
+		videoSpan.setError(result.Err)
+		videoSpan.end()
+		result.RateLimited = facts.hitRateLimit()
+		if wasRateLimited(result) {
+			w.limiter.reportRateLimit()
+		} else if result.Err == nil {
+			w.limiter.reportSuccess()
+		}
+	}()
+	if currentCfg.MaxCostStopsFetching && currentCfg.Usage.costExhausted() {
+		log.Printf("Video %s (%s): Skipped; the -max-cost budget has been reached.", v.ID, v.Title)
+		currentProcessingResult.Err = errBudgetExceeded
+		currentProcessingResult.Elapsed = time.Since(started)
+		return currentProcessingResult
+	}
+
+	if currentCfg.ThumbnailsDir != "" {
+		thumbnailPath, thumbnailErr := downloadThumbnail(ctx, w.thumbnailClient, v, currentCfg.ThumbnailsDir, currentCfg.TempPerms)
+		if thumbnailErr != nil {
+			log.Printf("Video %s (%s): Warning: %s", v.ID, v.Title, runWarnings.add(warnOutput, v.ID, "Skipping thumbnail: %v", thumbnailErr))
+		} else {
+			currentProcessingResult.ThumbnailPath = thumbnailPath
+			log.Printf("Video %s (%s): Saved thumbnail to %s.", v.ID, v.Title, thumbnailPath)
+		}
+	}
+
+	var transcript string
+	var transcriptErr error
+	fetchCtx, fetchSpan := w.tracer.start(ctx, "summify.fetch_transcript")
+	transcript, currentProcessingResult.TranscriptAttempts, transcriptErr = w.source.Fetch(fetchCtx, v) // transcriptErr
+	fetchSpan.setAttribute("video.id", v.ID)
+	fetchSpan.setAttribute("summify.attempts", currentProcessingResult.TranscriptAttempts)
+	fetchSpan.setError(transcriptErr)
+	fetchSpan.end()
+	if transcriptErr != nil {
+		log.Printf("Video %s (%s): Could not get transcript: %v", v.ID, v.Title, transcriptErr)
+		currentProcessingResult.Err = transcriptErr // Store the error object
+		currentProcessingResult.Elapsed = time.Since(started)
+		return currentProcessingResult
+	}
+
+	if transcript == "" {
+		log.Printf("Video %s (%s): No transcript found or extracted.", v.ID, v.Title)
+		currentProcessingResult.Err = errNoTranscript
+	} else {
+		log.Printf("Video %s (%s): Successfully fetched transcript.", v.ID, v.Title)
+		currentProcessingResult.TranscriptChars = utf8.RuneCountInString(transcript)
+		if currentProcessingResult.Manifest != nil {
+			currentProcessingResult.Manifest.TranscriptSHA256 = sha256Hex(transcript)
+		}
+		currentProcessingResult.SubtitleTrack = facts.subtitleLanguage()
+		transcriptLang := transcriptLanguage(currentProcessingResult.SubtitleTrack, transcript)
+		if currentCfg.GroupBy == groupByLanguage {
+			currentProcessingResult.Language = transcriptLang
+		}
+		currentProcessingResult.SummaryLanguage = summaryLanguage(transcriptLang, currentCfg)
+		minValLocal := func(a, b int) int {
+			if a < b {
+				return a
+			}
+			return b
+		}
+		log.Printf("  Transcript snippet for %s: %s...", v.ID, transcript[:minValLocal(100, len(transcript))])
+
+		if currentCfg.KeepTranscriptsDir != "" {
+			path, saveErr := saveTranscript(currentCfg.KeepTranscriptsDir, v.ID, transcript, currentCfg.TempPerms)
+			if saveErr != nil {
+				log.Printf("  Video %s (%s): Warning: %s", v.ID, v.Title, runWarnings.add(warnOutput, v.ID, "%v", saveErr))
+				if currentCfg.TranscriptsOnly {
+					currentProcessingResult.Err = saveErr
+				}
+			} else {
+				currentProcessingResult.TranscriptPath = path
+				log.Printf("  Video %s (%s): Saved transcript to %s.", v.ID, v.Title, path)
+			}
+		}
+
+		lowQuality := false
+		if currentCfg.MinQuality > 0 || trail != nil {
+			// Score the cue text as captioned, since -cite timestamps and
+			// speaker labels would count as punctuation.
+			qualityText := transcript
+			if plain := facts.plainTranscript(); plain != "" {
+				qualityText = plain
+			}
+			quality := measureTranscriptQuality(qualityText)
+			currentProcessingResult.Quality = &quality
+			lowQuality = currentCfg.MinQuality > 0 && quality.Score < currentCfg.MinQuality
+			currentProcessingResult.LowQuality = lowQuality && currentCfg.LowQualityAction == lowQualityFlag
+			log.Printf("  Video %s (%s): Transcript quality %s.", v.ID, v.Title, quality)
+			trail.note("transcript.quality", "%s", quality)
+		}
+
+		if currentCfg.UseChapters {
+			currentProcessingResult.Chapters = parseChapters(v.Description)
+			if len(currentProcessingResult.Chapters) > 0 {
+				log.Printf("  Video %s (%s): Found %d chapters in description.", v.ID, v.Title, len(currentProcessingResult.Chapters))
+			}
+		}
+
+		if currentCfg.TranscriptsOnly {
+			log.Printf("  Video %s (%s): Summarization skipped (-transcripts-only).", v.ID, v.Title)
+		} else if lowQuality && currentCfg.LowQualityAction == lowQualitySkip {
+			log.Printf("  Video %s (%s): Summarization skipped; transcript quality %.2f is below -min-quality %.2f.", v.ID, v.Title, currentProcessingResult.Quality.Score, currentCfg.MinQuality)
+			currentProcessingResult.Err = errLowQualityTranscript
+		} else if currentCfg.Estimate {
+			template, promptText, _ := selectPrompt(v, transcript, transcriptLang, currentCfg)
+			template = modelPromptTemplate(currentCfg.GeminiModel, template, currentCfg)
+			currentProcessingResult.EstimatedTokens = estimateTokens(buildSummaryPrompt(promptText, currentProcessingResult.Chapters, template, currentCfg))
+			log.Printf("  Video %s (%s): Estimated prompt size: %d tokens (-estimate, not summarized).", v.ID, v.Title, currentProcessingResult.EstimatedTokens)
+		} else if currentCfg.Usage.costExhausted() {
+			log.Printf("  Video %s (%s): Summarization skipped; the -max-cost budget has been reached.", v.ID, v.Title)
+			currentProcessingResult.Err = errBudgetExceeded
+		} else if currentGeminiClient != nil && !currentCfg.Usage.reserve(int64(currentProcessingResult.TranscriptChars*max(1, len(w.comparedModels)))) {
+			log.Printf("  Video %s (%s): Summarization skipped; the -max-total-chars budget has been reached.", v.ID, v.Title)
+			currentProcessingResult.Err = errCharBudgetExhausted
+		} else if currentGeminiClient != nil {
+			log.Printf("  Video %s (%s): Attempting to summarize transcript...", v.ID, v.Title)
+			var template, promptText string
+			template, promptText, currentProcessingResult.DescriptionIncluded = selectPrompt(v, transcript, transcriptLang, currentCfg)
+			if trail != nil {
+				templateSource := "built-in"
+				if template != "" {
+					templateSource = "-playlist-prompts or -language-prompts"
+				} else if modelPromptTemplate(currentCfg.GeminiModel, "", currentCfg) != "" {
+					templateSource = "-model-prompts"
+				}
+				trail.note("prompt", "%s template, description included: %t, audience: %q", templateSource, currentProcessingResult.DescriptionIncluded, currentCfg.Audience)
+			}
+			summarizeCtx, summarizeSpan := w.tracer.start(ctx, "summify.summarize")
+			summarizeCtx = withSummaryCall(summarizeCtx)
+			summarizeSpan.setAttribute("video.id", v.ID)
+			summarizeSpan.setAttribute("gemini.model", currentCfg.GeminiModel)
+			if len(w.comparedModels) > 0 {
+				currentProcessingResult.ModelSummaries = summarizeWithEachModel(summarizeCtx, w.comparedModels, v, promptText, currentProcessingResult.Chapters, template, currentCfg)
+				currentProcessingResult.Summary, currentProcessingResult.Err = firstModelSummary(currentProcessingResult.ModelSummaries)
+				currentProcessingResult.LLMAttempts, currentProcessingResult.Truncated = modelSummaryStats(currentProcessingResult.ModelSummaries)
+			} else if currentCfg.Structured {
+				extract, llmAttempts, extractErr := summarizeStructured(summarizeCtx, currentGeminiClient, v, promptText, currentProcessingResult.Chapters, template, currentCfg)
+				currentProcessingResult.LLMAttempts = llmAttempts
+				if extractErr != nil {
+					log.Printf("  Video %s (%s): Error summarizing: %v", v.ID, v.Title, extractErr)
+					currentProcessingResult.Err = extractErr
+				} else {
+					log.Printf("  Video %s (%s): Successfully summarized (structured).", v.ID, v.Title)
+					currentProcessingResult.Structured = extract
+					currentProcessingResult.Summary = renderExtract(extract)
+					log.Printf("  Summary for %s: %s", v.ID, extract.OneLiner)
+				}
+			} else {
+				summary, llmAttempts, summaryErr := summarizeTranscriptWithGemini(summarizeCtx, currentGeminiClient, promptText, currentProcessingResult.Chapters, template, currentCfg) // summaryErr
+				currentProcessingResult.LLMAttempts = llmAttempts
+				if isTruncatedResponse(summaryErr) {
+					log.Printf("  Video %s (%s): Warning: %s", v.ID, v.Title, runWarnings.add(warnSummary, v.ID, "%v", summaryErr))
+					currentProcessingResult.Summary = strings.TrimSpace(summary)
+					currentProcessingResult.Truncated = true
+				} else if summaryErr != nil {
+					log.Printf("  Video %s (%s): Error summarizing: %v", v.ID, v.Title, summaryErr)
+					currentProcessingResult.Err = summaryErr // Store error object
+				} else {
+					log.Printf("  Video %s (%s): Successfully summarized.", v.ID, v.Title)
+					currentProcessingResult.Summary = strings.TrimSpace(summary)
+					log.Printf("  Summary for %s: %s", v.ID, currentProcessingResult.Summary)
+				}
+			}
+			if currentCfg.Cite && currentProcessingResult.Summary != "" {
+				var dropped int
+				currentProcessingResult.Summary, currentProcessingResult.Citations, dropped = validateCitations(currentProcessingResult.Summary, transcript)
+				for i := range currentProcessingResult.ModelSummaries {
+					currentProcessingResult.ModelSummaries[i].Summary, _, _ = validateCitations(currentProcessingResult.ModelSummaries[i].Summary, transcript)
+				}
+				if dropped > 0 {
+					log.Printf("  Video %s (%s): Dropped %d citations past the end of the transcript.", v.ID, v.Title, dropped)
+				}
+				log.Printf("  Video %s (%s): Summary has %d timestamp citations.", v.ID, v.Title, currentProcessingResult.Citations)
+			}
+			summarizeSpan.setAttribute("summify.attempts", currentProcessingResult.LLMAttempts)
+			summarizeSpan.setError(currentProcessingResult.Err)
+			summarizeSpan.end()
+			if currentProcessingResult.Manifest != nil {
+				models := []string{currentCfg.GeminiModel}
+				if len(w.comparedModels) > 0 {
+					models = models[:0]
+					for _, model := range w.comparedModels {
+						models = append(models, model.name)
+					}
+				}
+				for _, model := range models {
+					currentProcessingResult.Manifest.Models = append(currentProcessingResult.Manifest.Models, manifestModel{Model: model, PromptSHA256: facts.promptHash(model)})
+				}
+			}
+			if currentCfg.Sections && currentProcessingResult.Summary != "" {
+				sections, ok := parseSections(currentProcessingResult.Summary, currentCfg.SectionHeaders)
+				if ok {
+					currentProcessingResult.Sections = sections
+				} else {
+					log.Printf("  Video %s (%s): Warning: %s", v.ID, v.Title, runWarnings.add(warnSummary, v.ID, "Summary is missing some -sections headers (%s); keeping it as a plain summary.", strings.Join(currentCfg.SectionHeaders, ", ")))
+				}
+			}
+			if currentCfg.Verify && currentProcessingResult.Summary != "" {
+				switch {
+				case currentCfg.VerifyMaxChars > 0 && utf8.RuneCountInString(promptText) > currentCfg.VerifyMaxChars:
+					log.Printf("  Video %s (%s): Warning: %s", v.ID, v.Title, runWarnings.add(warnSummary, v.ID, "Not verified; the transcript is longer than -verify-max-chars %d.", currentCfg.VerifyMaxChars))
+				case currentCfg.Usage.costExhausted():
+					log.Printf("  Video %s (%s): Verification skipped; the -max-cost budget has been reached.", v.ID, v.Title)
+				case !currentCfg.Usage.reserve(int64(utf8.RuneCountInString(promptText))):
+					log.Printf("  Video %s (%s): Verification skipped; the -max-total-chars budget has been reached.", v.ID, v.Title)
+				default:
+					claims, verifyErr := verifySummary(ctx, currentGeminiClient, promptText, currentProcessingResult.Summary, currentCfg)
+					if verifyErr != nil {
+						log.Printf("  Video %s (%s): Warning: %s", v.ID, v.Title, runWarnings.add(warnSummary, v.ID, "Could not verify the summary: %v", verifyErr))
+					} else {
+						currentProcessingResult.UnsupportedClaims = claims
+						log.Printf("  Video %s (%s): Verified the summary; %d unsupported claims.", v.ID, v.Title, len(claims))
+						trail.note("verify", "%d unsupported claims", len(claims))
+					}
+				}
+			}
+			if currentCfg.Retitle && currentProcessingResult.Summary != "" {
+				generatedTitle, titleErr := generateTitle(ctx, currentGeminiClient, v, currentProcessingResult.Summary, currentCfg)
+				if titleErr != nil {
+					log.Printf("  Video %s (%s): Warning: %s", v.ID, v.Title, runWarnings.add(warnSummary, v.ID, "Could not generate a title: %v", titleErr))
+				} else {
+					currentProcessingResult.GeneratedTitle = generatedTitle
+					log.Printf("  Video %s (%s): Generated title: %s", v.ID, v.Title, generatedTitle)
+				}
+			}
+			if currentCfg.NotesDir != "" && currentProcessingResult.Summary != "" {
+				noteSummary := currentProcessingResult.Summary
+				if len(currentProcessingResult.Sections) > 0 {
+					noteSummary = renderSections(currentProcessingResult.Sections, "###")
+				}
+				noteSummary = wrapSummary(noteSummary, v, currentCfg)
+				notesPath, notesErr := writeStudyNote(currentCfg.NotesDir, v, currentProcessingResult.GeneratedTitle, noteSummary, transcript, !currentCfg.NotesNoTranscript, currentCfg.RetitleFileNames, currentCfg.TempPerms)
+				if notesErr != nil {
+					log.Printf("  Video %s (%s): Warning: %s", v.ID, v.Title, runWarnings.add(warnOutput, v.ID, "%v", notesErr))
+				} else {
+					currentProcessingResult.NotesPath = notesPath
+					log.Printf("  Video %s (%s): Wrote study notes to %s.", v.ID, v.Title, notesPath)
+				}
+			}
+			if currentCfg.IncludeComments > 0 && w.youtube != nil {
+				commentsSummary, commentsErr := summarizeVideoComments(ctx, w.youtube, currentGeminiClient, v, currentCfg)
+				if commentsErr != nil {
+					log.Printf("  Video %s (%s): Warning: %s", v.ID, v.Title, runWarnings.add(warnSummary, v.ID, "Could not summarize comments: %v", commentsErr))
+				} else if commentsSummary != "" {
+					currentProcessingResult.CommentsSummary = commentsSummary
+					log.Printf("  Audience sentiment for %s: %s", v.ID, commentsSummary)
+				}
+			}
+		} else {
+			// Only set error if no other error has occurred yet for this video
+			if currentProcessingResult.Err == nil {
+				currentProcessingResult.Err = errSummarizerUnavailable
+			}
+			log.Printf("  Video %s (%s): Summarization skipped (Gemini client not available).", v.ID, v.Title)
+		}
+	}
+	if currentProcessingResult.Err == nil {
+		w.chain.record(v.ID, currentProcessingResult.Summary)
+	}
+	currentProcessingResult.Elapsed = time.Since(started)
+	return currentProcessingResult
+}
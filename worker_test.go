@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/option"
+)
+
+// fakeTranscriptSource returns a fixed transcript, reporting lang as the
+// subtitle track used and plain as the unformatted cue text the way the
+// yt-dlp source does.
+type fakeTranscriptSource struct {
+	transcript string
+	attempts   int
+	err        error
+	lang       string
+	plain      string
+}
+
+func (s fakeTranscriptSource) Fetch(ctx context.Context, video VideoDetails) (string, int, error) {
+	videoFactsFrom(ctx).setSubtitleLanguage(s.lang)
+	videoFactsFrom(ctx).setPlainTranscript(s.plain)
+	return s.transcript, s.attempts, s.err
+}
+
+func newTestWorker(source TranscriptSource) *videoWorker {
+	return &videoWorker{source: source, limiter: newConcurrencyLimiter(1, 1)}
+}
+
+func newTestConfig() *AppConfig {
+	return &AppConfig{SummaryWordCount: 100, TranscriptJoin: transcriptJoinSpace, TempPerms: 0755}
+}
+
+// newTestGeminiModel returns a Gemini model named name whose requests go to
+// handler instead of the Gemini API.
+func newTestGeminiModel(t *testing.T, name string, handler http.HandlerFunc) *rotatingGeminiModel {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	client, err := genai.NewClient(context.Background(), option.WithAPIKey("test"), option.WithEndpoint(server.URL), option.WithHTTPClient(server.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return &rotatingGeminiModel{keys: newAPIKeyRing("Gemini", []string{"test"}), modelName: name, client: client, model: client.GenerativeModel(name)}
+}
+
+// geminiReply answers every generateContent request with text.
+func geminiReply(text string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"` + text + `"}]},"finishReason":"STOP"}]}`))
+	}
+}
+
+func TestVideoWorkerFetchFailures(t *testing.T) {
+	fetchErr := errors.New("yt-dlp failed")
+	tests := []struct {
+		name   string
+		source fakeTranscriptSource
+		want   error
+	}{
+		{"fetch error", fakeTranscriptSource{attempts: 3, err: fetchErr}, fetchErr},
+		{"empty transcript", fakeTranscriptSource{attempts: 1}, errNoTranscript},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := newTestWorker(tt.source).process(context.Background(), VideoDetails{ID: "abc", Title: "Test"}, newTestConfig(), nil)
+			if !errors.Is(result.Err, tt.want) {
+				t.Errorf("Err = %v, want %v", result.Err, tt.want)
+			}
+			if result.TranscriptAttempts != tt.source.attempts {
+				t.Errorf("TranscriptAttempts = %d, want %d", result.TranscriptAttempts, tt.source.attempts)
+			}
+			if result.Summary != "" {
+				t.Errorf("Summary = %q, want none", result.Summary)
+			}
+		})
+	}
+}
+
+func TestVideoWorkerWithoutGemini(t *testing.T) {
+	source := fakeTranscriptSource{transcript: "hallo und willkommen", attempts: 1, lang: "de-orig"}
+	result := newTestWorker(source).process(context.Background(), VideoDetails{ID: "abc", Title: "Test"}, newTestConfig(), nil)
+	if !errors.Is(result.Err, errSummarizerUnavailable) {
+		t.Errorf("Err = %v, want %v", result.Err, errSummarizerUnavailable)
+	}
+	if result.TranscriptChars != len(source.transcript) {
+		t.Errorf("TranscriptChars = %d, want %d", result.TranscriptChars, len(source.transcript))
+	}
+	if result.SubtitleTrack != "de-orig" || result.SummaryLanguage != "en" {
+		t.Errorf("SubtitleTrack, SummaryLanguage = %q, %q; want \"de-orig\", \"en\"", result.SubtitleTrack, result.SummaryLanguage)
+	}
+}
+
+func TestVideoWorkerTranscriptsOnly(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.TranscriptsOnly = true
+	cfg.KeepTranscriptsDir = t.TempDir()
+	source := fakeTranscriptSource{transcript: "hello world", attempts: 1}
+	result := newTestWorker(source).process(context.Background(), VideoDetails{ID: "abc", Title: "Test"}, cfg, nil)
+	if result.Err != nil {
+		t.Fatalf("Err = %v, want nil", result.Err)
+	}
+	data, err := os.ReadFile(result.TranscriptPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != source.transcript {
+		t.Errorf("saved transcript = %q, want %q", data, source.transcript)
+	}
+}
+
+func TestVideoWorkerEstimate(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Estimate = true
+	source := fakeTranscriptSource{transcript: "hello world, this is a test transcript", attempts: 1}
+	result := newTestWorker(source).process(context.Background(), VideoDetails{ID: "abc", Title: "Test"}, cfg, nil)
+	if result.Err != nil {
+		t.Fatalf("Err = %v, want nil", result.Err)
+	}
+	if result.EstimatedTokens == 0 {
+		t.Error("EstimatedTokens = 0, want the estimated prompt size")
+	}
+}
+
+func TestVideoWorkerQualityUsesPlainText(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.TranscriptsOnly = true
+	cfg.MinQuality = 0.5
+	source := fakeTranscriptSource{
+		transcript: "[00:01] so um we we go here\n[00:04] and um we we go there\n[00:07] so um we we go here",
+		plain:      "so um we we go here and um we we go there so um we we go here",
+		attempts:   1,
+	}
+	result := newTestWorker(source).process(context.Background(), VideoDetails{ID: "abc", Title: "Test"}, cfg, nil)
+	if result.Quality == nil {
+		t.Fatal("Quality = nil, want a score")
+	}
+	if want := measureTranscriptQuality(source.plain); *result.Quality != want {
+		t.Errorf("Quality = %v, want the plain text's %v", *result.Quality, want)
+	}
+}
+
+func TestVideoWorkerCompareModelsIsNotRateLimited(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.LLMTimeout = 10 * time.Second
+	first := newTestGeminiModel(t, "model-a", geminiReply("Summary from a."))
+	second := newTestGeminiModel(t, "model-b", geminiReply("Summary from b."))
+	worker := newTestWorker(fakeTranscriptSource{transcript: "hello world", attempts: 1})
+	worker.limiter = newConcurrencyLimiter(1, 4)
+	worker.comparedModels = []comparedModel{{name: "model-a", gemini: first}, {name: "model-b", gemini: second}}
+
+	result := worker.process(context.Background(), VideoDetails{ID: "abc", Title: "Test"}, cfg, first)
+	if result.Err != nil {
+		t.Fatalf("Err = %v, want nil", result.Err)
+	}
+	for _, s := range result.ModelSummaries {
+		if s.Attempts != 1 {
+			t.Errorf("%s Attempts = %d, want 1", s.Model, s.Attempts)
+		}
+	}
+	if result.LLMAttempts != 1 {
+		t.Errorf("LLMAttempts = %d, want 1", result.LLMAttempts)
+	}
+	if wasRateLimited(result) {
+		t.Error("wasRateLimited() = true, want false for a comparison without retries")
+	}
+	if worker.limiter.limit != 4 {
+		t.Errorf("limiter limit = %d, want 4", worker.limiter.limit)
+	}
+}
+
+func TestVideoWorkerReportsRateLimit(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.LLMTimeout = 10 * time.Second
+	requests := 0
+	gemini := newTestGeminiModel(t, "model-a", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error":{"code":429,"message":"slow down","status":"RESOURCE_EXHAUSTED"}}`))
+			return
+		}
+		geminiReply("A summary.")(w, r)
+	})
+	worker := newTestWorker(fakeTranscriptSource{transcript: "hello world", attempts: 1})
+	worker.limiter = newConcurrencyLimiter(1, 4)
+
+	result := worker.process(context.Background(), VideoDetails{ID: "abc", Title: "Test"}, cfg, gemini)
+	if result.Err != nil {
+		t.Fatalf("Err = %v, want nil", result.Err)
+	}
+	if result.LLMAttempts != 2 || !result.RateLimited {
+		t.Errorf("LLMAttempts, RateLimited = %d, %v; want 2, true", result.LLMAttempts, result.RateLimited)
+	}
+	if worker.limiter.limit != 2 {
+		t.Errorf("limiter limit = %d, want 2", worker.limiter.limit)
+	}
+}
+
+func TestWasRateLimitedIgnoresKeyRotation(t *testing.T) {
+	if wasRateLimited(ProcessingResult{LLMAttempts: 2}) {
+		t.Error("wasRateLimited() = true for a retry on the next key, want false")
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// --- Summary Prefix and Suffix ---
+
+// summaryTemplateData is what -summary-prefix and -summary-suffix templates
+// can refer to.
+type summaryTemplateData struct {
+	URL   string
+	Title string
+	Date  string // The video's publish date, or the run's date when unknown
+}
+
+// parseSummaryTemplate parses a -summary-prefix or -summary-suffix value and
+// checks it against sample data, so a typo such as {{.Url}} fails at startup
+// rather than on every video. An empty value returns nil.
+func parseSummaryTemplate(flagName, text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+	tmpl, err := template.New(flagName).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -%s template: %w", flagName, err)
+	}
+	if err := tmpl.Execute(&strings.Builder{}, summaryTemplateData{}); err != nil {
+		return nil, fmt.Errorf("invalid -%s template: %w", flagName, err)
+	}
+	return tmpl, nil
+}
+
+// summaryWrapping renders the -summary-prefix and -summary-suffix text for
+// video; either is "" when its flag is unset.
+func summaryWrapping(video VideoDetails, cfg *AppConfig) (prefix, suffix string) {
+	if cfg.SummaryPrefix == nil && cfg.SummarySuffix == nil {
+		return "", ""
+	}
+	date := video.PublishedAt
+	if date.IsZero() {
+		date = time.Now()
+	}
+	data := summaryTemplateData{
+		URL:   "https://www.youtube.com/watch?v=" + video.ID,
+		Title: video.Title,
+		Date:  date.Format("2006-01-02"),
+	}
+	return executeSummaryTemplate(cfg.SummaryPrefix, data), executeSummaryTemplate(cfg.SummarySuffix, data)
+}
+
+// wrapSummary adds the -summary-prefix and -summary-suffix text around
+// summary, each separated from it by a blank line. An empty summary is
+// returned as it is.
+func wrapSummary(summary string, video VideoDetails, cfg *AppConfig) string {
+	if summary == "" {
+		return summary
+	}
+	prefix, suffix := summaryWrapping(video, cfg)
+	parts := []string{summary}
+	if prefix != "" {
+		parts = append([]string{prefix}, parts...)
+	}
+	if suffix != "" {
+		parts = append(parts, suffix)
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// wrapExtract returns a copy of extract carrying the rendered prefix and
+// suffix, since a JSON object has no text to put them around. A nil extract
+// stays nil.
+func wrapExtract(extract *Extract, video VideoDetails, cfg *AppConfig) *Extract {
+	if extract == nil {
+		return nil
+	}
+	wrapped := *extract
+	wrapped.Prefix, wrapped.Suffix = summaryWrapping(video, cfg)
+	return &wrapped
+}
+
+// wrapResult returns a copy of result for writing out, with its summary,
+// each -compare-models summary and its -structured object wrapped. The
+// results themselves keep the raw summary, which -manifest checksums,
+// -embeddings, -dedupe and -series-context use.
+func wrapResult(result ProcessingResult, cfg *AppConfig) ProcessingResult {
+	if cfg.SummaryPrefix == nil && cfg.SummarySuffix == nil {
+		return result
+	}
+	video := result.VideoDetails
+	result.Summary = wrapSummary(result.Summary, video, cfg)
+	result.Structured = wrapExtract(result.Structured, video, cfg)
+	if result.ModelSummaries != nil {
+		summaries := make([]ModelSummary, len(result.ModelSummaries))
+		for i, s := range result.ModelSummaries {
+			s.Summary = wrapSummary(s.Summary, video, cfg)
+			summaries[i] = s
+		}
+		result.ModelSummaries = summaries
+	}
+	return result
+}
+
+// wrapResults applies wrapResult to every result.
+func wrapResults(allResults map[string]ProcessingResult, cfg *AppConfig) map[string]ProcessingResult {
+	wrapped := make(map[string]ProcessingResult, len(allResults))
+	for id, result := range allResults {
+		wrapped[id] = wrapResult(result, cfg)
+	}
+	return wrapped
+}
+
+// executeSummaryTemplate renders tmpl, which parseSummaryTemplate has
+// already checked, returning "" for a nil template.
+func executeSummaryTemplate(tmpl *template.Template, data summaryTemplateData) string {
+	if tmpl == nil {
+		return ""
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(b.String())
+}
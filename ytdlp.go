@@ -0,0 +1,401 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// --- yt-dlp Failure Classification ---
+
+// ytDlpCommand is the executable used to fetch subtitles. It is a variable so
+// that a stub command can be substituted when exercising the retry logic.
+var ytDlpCommand = "yt-dlp"
+
+// yt-dlp exit codes with a documented meaning. Everything else (including the
+// generic 1) is classified by the command's output.
+const (
+	ytDlpExitUsageError      = 2   // Invalid options
+	ytDlpExitRestartRequired = 100 // yt-dlp updated itself and must be rerun
+	ytDlpExitCancelled       = 101 // Download cancelled by --max-downloads etc.
+)
+
+// Subtitle formats yt-dlp is asked for. VTT is the default; SRT is the
+// alternate route for -retry-empty-transcript. YouTube serves no SRT, so it
+// is converted from the best available format with ffmpeg.
+const (
+	subtitleFormatVTT = "vtt"
+	subtitleFormatSRT = "srt"
+)
+
+// ytDlpArgs builds the yt-dlp arguments that download a video's English
+// subtitles in format into the temp transcript directory. Original-language
+// auto tracks ("de-orig") come along to detect machine-translated English;
+// pickSubtitleFile only uses them under -no-autotranslate or -same-language.
+func ytDlpArgs(cfg *AppConfig, videoURL, proxy, format string) []string {
+	args := []string{"--write-auto-sub", "--write-sub"}
+	if format == subtitleFormatVTT {
+		args = append(args, "--sub-format", format)
+	} else {
+		args = append(args, "--sub-format", "best", "--convert-subs", format)
+	}
+	args = append(args,
+		"--sub-langs", "en.*,en,.*-orig",
+		"--skip-download",
+		"-o", filepath.Join(cfg.TempTranscriptDir, "%(id)s.%(ext)s"),
+	)
+	if cfg.YtDlpUserAgent != "" {
+		args = append(args, "--user-agent", cfg.YtDlpUserAgent)
+	}
+	for _, header := range cfg.YtDlpHeaders {
+		args = append(args, "--add-header", header)
+	}
+	if proxy != "" {
+		args = append(args, "--proxy", proxy)
+	}
+	if cfg.SkipSponsors {
+		args = append(args, sponsorBlockArgs(cfg.TempTranscriptDir)...)
+	}
+	return append(args, videoURL)
+}
+
+// ytDlpOutput is what one yt-dlp run printed. yt-dlp writes its progress
+// and informational messages to stdout and warnings and errors to stderr, so
+// failures are classified from stderr alone.
+type ytDlpOutput struct {
+	stdout string
+	stderr string
+}
+
+// combined returns both streams for logging, stdout first. Unlike
+// CombinedOutput the interleaving of the two is not preserved.
+func (o ytDlpOutput) combined() string {
+	if o.stdout == "" || o.stderr == "" {
+		return o.stdout + o.stderr
+	}
+	return strings.TrimRight(o.stdout, "\n") + "\n" + o.stderr
+}
+
+// runYtDlp runs yt-dlp with args, capturing stdout and stderr separately.
+func runYtDlp(ctx context.Context, args []string) (ytDlpOutput, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, ytDlpCommand, args...)
+	cmd.Stderr = &stderr
+	stdout, err := cmd.Output()
+	return ytDlpOutput{stdout: string(stdout), stderr: stderr.String()}, err
+}
+
+// redactYtDlpArgs returns a copy of args that is safe to log: custom header
+// values may carry cookies or tokens, so only their names are kept, and proxy
+// passwords are masked.
+func redactYtDlpArgs(args []string) []string {
+	redacted := append([]string(nil), args...)
+	for i := 1; i < len(redacted); i++ {
+		switch redacted[i-1] {
+		case "--add-header":
+			name, _, _ := strings.Cut(redacted[i], ":")
+			redacted[i] = name + ":<redacted>"
+		case "--proxy":
+			redacted[i] = redactProxy(redacted[i])
+		}
+	}
+	return redacted
+}
+
+// redactProxy masks the password in a proxy URL for logging.
+func redactProxy(proxy string) string {
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return "<unparseable proxy>"
+	}
+	return u.Redacted()
+}
+
+// proxyRotation hands out the configured yt-dlp proxies round-robin, one per
+// invocation, so that a large run spreads its requests across all of them.
+type proxyRotation struct {
+	mu      sync.Mutex
+	proxies []string
+	next    int
+}
+
+func newProxyRotation(proxies []string) *proxyRotation {
+	return &proxyRotation{proxies: proxies}
+}
+
+// pick returns the proxy for the next yt-dlp run, or "" (direct connection)
+// when no proxies are configured.
+func (p *proxyRotation) pick() string {
+	if p == nil || len(p.proxies) == 0 {
+		return ""
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	proxy := p.proxies[p.next]
+	p.next = (p.next + 1) % len(p.proxies)
+	return proxy
+}
+
+// fetchFailureKind categorizes why a yt-dlp invocation failed.
+type fetchFailureKind int
+
+const (
+	failureUnknown fetchFailureKind = iota
+	failureNetwork
+	failureNotFound
+	failureAuth
+	failureUsage
+	failureMissingTool
+	failureRestart
+)
+
+func (k fetchFailureKind) String() string {
+	switch k {
+	case failureNetwork:
+		return "network"
+	case failureNotFound:
+		return "not found"
+	case failureAuth:
+		return "authentication"
+	case failureUsage:
+		return "usage"
+	case failureMissingTool:
+		return "missing yt-dlp"
+	case failureRestart:
+		return "restart required"
+	default:
+		return "unknown"
+	}
+}
+
+// retryable reports whether another attempt could plausibly succeed. Unknown
+// failures stay retryable to preserve the previous retry-everything behaviour,
+// and a yt-dlp that updated itself succeeds when simply run again.
+func (k fetchFailureKind) retryable() bool {
+	return k == failureNetwork || k == failureUnknown || k == failureRestart
+}
+
+// Output fragments, matched case-insensitively, that identify each category.
+var (
+	networkFailurePatterns = []string{
+		"unable to download webpage", "timed out", "connection reset", "connection refused",
+		"temporary failure in name resolution", "network is unreachable", "http error 429",
+		"http error 500", "http error 502", "http error 503", "http error 504",
+	}
+	notFoundFailurePatterns = []string{
+		"video unavailable", "private video", "this video is private", "has been removed",
+		"does not exist", "http error 404", "is not a valid url",
+	}
+	authFailurePatterns = []string{
+		"sign in to confirm", "login required", "members-only", "join this channel",
+		"age-restricted", "confirm your age", "http error 403",
+	}
+)
+
+// classifyYtDlpFailure combines the process exit code with known message
+// patterns to decide what kind of failure a yt-dlp run hit. matched is the
+// pattern that decided it, or "" when the exit code did.
+func classifyYtDlpFailure(err error, output string) (kind fetchFailureKind, matched string) {
+	if errors.Is(err, exec.ErrNotFound) {
+		return failureMissingTool, ""
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		switch exitErr.ExitCode() {
+		case ytDlpExitUsageError, ytDlpExitCancelled:
+			return failureUsage, ""
+		case ytDlpExitRestartRequired:
+			return failureRestart, ""
+		case -1: // Killed by a signal, e.g. a stalled connection was interrupted
+			return failureNetwork, ""
+		}
+	}
+
+	lowerOutput := strings.ToLower(output)
+	for _, category := range []struct {
+		kind     fetchFailureKind
+		patterns []string
+	}{
+		{failureNotFound, notFoundFailurePatterns},
+		{failureAuth, authFailurePatterns},
+		{failureNetwork, networkFailurePatterns},
+	} {
+		if pattern := matchingPattern(lowerOutput, category.patterns); pattern != "" {
+			return category.kind, pattern
+		}
+	}
+	return failureUnknown, ""
+}
+
+// shouldRetryYtDlp decides whether a failed yt-dlp run is retried. Without
+// -retry-on (retryOn is nil) the failure kind decides. With it, a run that
+// the exit code marks as a usage error or missing tool still fails fast, one
+// killed by a signal or asking to be rerun after an update is still
+// retried, and otherwise only output containing
+// one of the retryOn substrings is retried. matched is the pattern that
+// decided, if any.
+func shouldRetryYtDlp(err error, output string, retryOn []string) (retry bool, kind fetchFailureKind, matched string) {
+	kind, matched = classifyYtDlpFailure(err, output)
+	if retryOn == nil {
+		return kind.retryable(), kind, matched
+	}
+	switch {
+	case kind == failureUsage || kind == failureMissingTool:
+		return false, kind, ""
+	case kind == failureRestart || (kind == failureNetwork && matched == ""):
+		return true, kind, ""
+	}
+	matched = matchingPattern(strings.ToLower(output), retryOn)
+	return matched != "", kind, matched
+}
+
+// matchingPattern returns the first of patterns that s contains, or "".
+func matchingPattern(s string, patterns []string) string {
+	for _, pattern := range patterns {
+		if strings.Contains(s, pattern) {
+			return pattern
+		}
+	}
+	return ""
+}
+
+// --- Subtitle Track Selection ---
+
+// findSubtitleFiles returns the non-empty subtitle files in format that
+// yt-dlp wrote for videoID: "<videoID>.<lang>.<format>", or
+// "<videoID>.<format>" when no language tag was added.
+func findSubtitleFiles(dir, videoID, format string) []string {
+	candidates, _ := filepath.Glob(filepath.Join(dir, videoID+".*."+format))
+	candidates = append(candidates, filepath.Join(dir, videoID+"."+format))
+	var files []string
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); err == nil && info.Mode().IsRegular() && info.Size() > 0 {
+			files = append(files, candidate)
+		}
+	}
+	return files
+}
+
+// reportsNoSubtitles reports whether yt-dlp's output says the video has no
+// matching subtitles. It only classifies runs that wrote no file.
+func reportsNoSubtitles(output string) bool {
+	return strings.Contains(output, "no subtitles") || strings.Contains(output, "no suitable subtitles found")
+}
+
+// subtitleLanguage extracts the language tag from a yt-dlp subtitle file name
+// such as "<videoID>.en-US.vtt". The plain "<videoID>.vtt" fallback has none.
+func subtitleLanguage(path, videoID string) string {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return strings.TrimPrefix(strings.TrimPrefix(name, videoID), ".")
+}
+
+// subtitleLanguageRank orders subtitle tracks from most to least preferred:
+// plain "en", then "en-US", then other English variants, then the
+// auto-generated "en-orig" track, with original tracks in other languages
+// ("de-orig") last. pickSubtitleFile only considers those under
+// -no-autotranslate or -same-language.
+func subtitleLanguageRank(lang string) int {
+	switch {
+	case lang == "en-orig":
+		return 4
+	case strings.HasSuffix(lang, "-orig"):
+		return 5
+	case lang == "en":
+		return 0
+	case lang == "en-US":
+		return 1
+	case strings.HasPrefix(lang, "en-"):
+		return 2
+	default:
+		return 3
+	}
+}
+
+// isForeignOriginalTrack reports whether lang is an original-language auto
+// track in a language other than English, such as "de-orig".
+func isForeignOriginalTrack(lang string) bool {
+	return strings.HasSuffix(lang, "-orig") && lang != "en-orig"
+}
+
+// autoTranslatedFrom reports the source language when the English tracks are
+// most likely YouTube's machine translation: an English track was written,
+// an original-language auto track ("xx-orig") exists for a language other
+// than English and there is no "en-orig". It returns "" otherwise.
+func autoTranslatedFrom(langs []string) string {
+	source := ""
+	english := false
+	for _, lang := range langs {
+		switch {
+		case lang == "en-orig":
+			return ""
+		case isForeignOriginalTrack(lang):
+			if source == "" {
+				source = strings.TrimSuffix(lang, "-orig")
+			}
+		case lang == "en" || strings.HasPrefix(lang, "en-"):
+			english = true
+		}
+	}
+	if !english {
+		return ""
+	}
+	return source
+}
+
+// pickSubtitleFile returns the preferred subtitle file among the ones yt-dlp
+// wrote for a video, together with its language tag, why it was preferred
+// and, when the English tracks look auto-translated, the language they were
+// translated from. With avoidTranslation set such a video's original-language
+// track is used instead. Original-language tracks in other languages are
+// only candidates with avoidTranslation set; path is "" when nothing else
+// was written.
+func pickSubtitleFile(matches []string, videoID string, avoidTranslation bool) (path, lang, reason, translatedFrom string) {
+	var sorted []string
+	langs := make([]string, len(matches))
+	for i, match := range matches {
+		langs[i] = subtitleLanguage(match, videoID)
+		if avoidTranslation || !isForeignOriginalTrack(langs[i]) {
+			sorted = append(sorted, match)
+		}
+	}
+	if len(sorted) == 0 {
+		return "", "", "", ""
+	}
+	translatedFrom = autoTranslatedFrom(langs)
+
+	rank := func(path string) int {
+		lang := subtitleLanguage(path, videoID)
+		if avoidTranslation && translatedFrom != "" && lang == translatedFrom+"-orig" {
+			return -1
+		}
+		return subtitleLanguageRank(lang)
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		ri, rj := rank(sorted[i]), rank(sorted[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return sorted[i] < sorted[j]
+	})
+	path, lang = sorted[0], subtitleLanguage(sorted[0], videoID)
+	switch {
+	case len(matches) == 1:
+		reason = "only track available"
+	case rank(path) < 0:
+		reason = "original-language track instead of the auto-translated English (-no-autotranslate or -same-language)"
+	case lang == "en":
+		reason = "plain English is preferred"
+	case strings.HasPrefix(lang, "en"):
+		reason = "best English variant available"
+	default:
+		reason = "no English track; first track by language"
+	}
+	return path, lang, reason, translatedFrom
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// stubYtDlp points ytDlpCommand at a shell script that writes stderr to
+// standard error and exits with code, standing in for yt-dlp. When
+// STUB_WRITE is set the script first writes STUB_CONTENT to that path, as
+// yt-dlp writes a subtitle file.
+func stubYtDlp(t *testing.T, code, stderr string) {
+	t.Helper()
+	script := filepath.Join(t.TempDir(), "yt-dlp")
+	body := "#!/bin/sh\nprintf '%s' \"$STUB_STDERR\" >&2\n"
+	body += "if [ -n \"$STUB_WRITE\" ]; then printf '%s' \"$STUB_CONTENT\" > \"$STUB_WRITE\"; fi\n"
+	if code == "signal" {
+		body += "kill -9 $$\n"
+	} else {
+		body += "exit " + code + "\n"
+	}
+	if err := os.WriteFile(script, []byte(body), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("STUB_STDERR", stderr)
+	previous := ytDlpCommand
+	ytDlpCommand = script
+	t.Cleanup(func() { ytDlpCommand = previous })
+}
+
+func TestClassifyYtDlpFailure(t *testing.T) {
+	tests := []struct {
+		name      string
+		code      string
+		stderr    string
+		wantKind  fetchFailureKind
+		wantMatch string
+		wantRetry bool
+	}{
+		{"usage error", "2", "yt-dlp: error: no such option: --bogus", failureUsage, "", false},
+		{"restart required", "100", "", failureRestart, "", true},
+		{"cancelled", "101", "", failureUsage, "", false},
+		{"killed by signal", "signal", "", failureNetwork, "", true},
+		{"rate limited", "1", "ERROR: Unable to download webpage: HTTP Error 429: Too Many Requests", failureNetwork, "unable to download webpage", true},
+		{"timeout", "1", "ERROR: The read operation timed out", failureNetwork, "timed out", true},
+		{"server error", "1", "ERROR: HTTP Error 503: Service Unavailable", failureNetwork, "http error 503", true},
+		{"unavailable", "1", "ERROR: [youtube] abc: Video unavailable", failureNotFound, "video unavailable", false},
+		{"private", "1", "ERROR: [youtube] abc: Private video", failureNotFound, "private video", false},
+		{"sign in", "1", "ERROR: Sign in to confirm you're not a bot", failureAuth, "sign in to confirm", false},
+		{"members only", "1", "ERROR: Join this channel to get access to members-only content", failureAuth, "members-only", false},
+		{"unrecognized", "1", "ERROR: something new went wrong", failureUnknown, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubYtDlp(t, tt.code, tt.stderr)
+			output, err := runYtDlp(context.Background(), nil)
+			if err == nil {
+				t.Fatal("stub yt-dlp succeeded; want a failure")
+			}
+			kind, matched := classifyYtDlpFailure(err, output.stderr)
+			if kind != tt.wantKind || matched != tt.wantMatch {
+				t.Errorf("classifyYtDlpFailure = %v, %q; want %v, %q", kind, matched, tt.wantKind, tt.wantMatch)
+			}
+			if retry, _, _ := shouldRetryYtDlp(err, output.stderr, nil); retry != tt.wantRetry {
+				t.Errorf("shouldRetryYtDlp = %t; want %t", retry, tt.wantRetry)
+			}
+		})
+	}
+}
+
+func TestClassifyYtDlpFailureMissingTool(t *testing.T) {
+	previous := ytDlpCommand
+	ytDlpCommand = "summify-no-such-yt-dlp"
+	t.Cleanup(func() { ytDlpCommand = previous })
+	_, err := runYtDlp(context.Background(), nil)
+	if kind, _ := classifyYtDlpFailure(err, ""); kind != failureMissingTool {
+		t.Errorf("classifyYtDlpFailure = %v; want %v", kind, failureMissingTool)
+	}
+}
+
+func TestShouldRetryYtDlpWithRetryOn(t *testing.T) {
+	retryOn := []string{"remote end closed"}
+	tests := []struct {
+		name      string
+		code      string
+		stderr    string
+		wantRetry bool
+		wantMatch string
+	}{
+		{"listed substring", "1", "ERROR: Remote end closed connection", true, "remote end closed"},
+		{"built-in network pattern not listed", "1", "ERROR: HTTP Error 503", false, ""},
+		{"usage error", "2", "remote end closed", false, ""},
+		{"restart required", "100", "", true, ""},
+		{"killed by signal", "signal", "", true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubYtDlp(t, tt.code, tt.stderr)
+			output, err := runYtDlp(context.Background(), nil)
+			retry, _, matched := shouldRetryYtDlp(err, output.stderr, retryOn)
+			if retry != tt.wantRetry || matched != tt.wantMatch {
+				t.Errorf("shouldRetryYtDlp = %t, %q; want %t, %q", retry, matched, tt.wantRetry, tt.wantMatch)
+			}
+		})
+	}
+}
+
+func TestYtDlpArgsSubtitleFormat(t *testing.T) {
+	cfg := &AppConfig{TempTranscriptDir: t.TempDir()}
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{subtitleFormatVTT, []string{"--sub-format", "vtt"}},
+		{subtitleFormatSRT, []string{"--sub-format", "best", "--convert-subs", "srt"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			args := strings.Join(ytDlpArgs(cfg, "https://www.youtube.com/watch?v=abc", "", tt.format), " ")
+			if want := strings.Join(tt.want, " "); !strings.Contains(args, want) {
+				t.Errorf("ytDlpArgs(%q) = %q, want it to contain %q", tt.format, args, want)
+			}
+		})
+	}
+}
+
+func TestFetchVideoTranscriptFilePresence(t *testing.T) {
+	tests := []struct {
+		name         string
+		code         string
+		stderr       string
+		content      string // Written as the video's English subtitles unless "-"
+		want         string
+		wantAttempts int
+		wantErr      bool
+	}{
+		{"clean exit with file", "0", "", testVTT, "hello  world  second   line and more", 1, false},
+		{"failed exit with file", "1", "ERROR: something new went wrong", testVTT, "hello  world  second   line and more", 1, false},
+		{"clean exit without file", "0", "", "-", "", 1, false},
+		{"clean exit with empty file", "0", "", "", "", 1, false},
+		{"no subtitles reported", "1", "ERROR: no subtitles for the requested languages", "-", "", 1, false},
+		{"failure without file", "1", "ERROR: something new went wrong", "-", "", 2, true},
+		{"usage error without file", "2", "yt-dlp: error: no such option", "-", "", 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.TempTranscriptDir = t.TempDir()
+			cfg.MaxTranscriptRetries = 2
+			stubYtDlp(t, tt.code, tt.stderr)
+			if tt.content != "-" {
+				t.Setenv("STUB_WRITE", filepath.Join(cfg.TempTranscriptDir, "abc.en.vtt"))
+				t.Setenv("STUB_CONTENT", tt.content)
+			}
+			transcript, attempts, err := fetchVideoTranscript(context.Background(), "abc", subtitleFormatVTT, cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("fetchVideoTranscript error = %v, want error: %t", err, tt.wantErr)
+			}
+			if transcript != tt.want {
+				t.Errorf("transcript = %q, want %q", transcript, tt.want)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestPickSubtitleFileReason(t *testing.T) {
+	tests := []struct {
+		name               string
+		matches            []string
+		avoidTranslation   bool
+		wantLang           string
+		wantReason         string
+		wantTranslatedFrom string
+	}{
+		{"single track", []string{"abc.fr.vtt"}, false, "fr", "only track available", ""},
+		{"plain English", []string{"abc.en-GB.vtt", "abc.en.vtt"}, false, "en", "plain English is preferred", ""},
+		{"English variant", []string{"abc.fr.vtt", "abc.en-GB.vtt"}, false, "en-GB", "best English variant available", ""},
+		{"no English", []string{"abc.fr.vtt", "abc.de.vtt"}, false, "de", "no English track; first track by language", ""},
+		{"translated kept", []string{"abc.en.vtt", "abc.de-orig.vtt"}, false, "en", "plain English is preferred", "de"},
+		{"translated avoided", []string{"abc.en.vtt", "abc.de-orig.vtt"}, true, "de-orig", "original-language track instead of the auto-translated English (-no-autotranslate or -same-language)", "de"},
+		{"English original track", []string{"abc.en.vtt", "abc.en-orig.vtt", "abc.de-orig.vtt"}, true, "en", "plain English is preferred", ""},
+		{"only original track", []string{"abc.de-orig.vtt"}, false, "", "", ""},
+		{"only original track avoided", []string{"abc.de-orig.vtt"}, true, "de-orig", "only track available", ""},
+		{"original track beside another language", []string{"abc.fr.vtt", "abc.de-orig.vtt"}, false, "fr", "no English track; first track by language", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, lang, reason, translatedFrom := pickSubtitleFile(tt.matches, "abc", tt.avoidTranslation)
+			if lang != tt.wantLang || reason != tt.wantReason || translatedFrom != tt.wantTranslatedFrom {
+				t.Errorf("pickSubtitleFile() = %q, %q, %q, want %q, %q, %q", lang, reason, translatedFrom, tt.wantLang, tt.wantReason, tt.wantTranslatedFrom)
+			}
+		})
+	}
+}
+
+func TestSubtitleLanguageRank(t *testing.T) {
+	order := []string{"en", "en-US", "en-GB", "fr", "en-orig", "de-orig"}
+	for i := 1; i < len(order); i++ {
+		if subtitleLanguageRank(order[i-1]) >= subtitleLanguageRank(order[i]) {
+			t.Errorf("subtitleLanguageRank(%q) >= subtitleLanguageRank(%q), want %q preferred", order[i-1], order[i], order[i-1])
+		}
+	}
+}